@@ -0,0 +1,127 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bufbuild/connect-go"
+	"github.com/hadrienk/connect-go-interceptors/common"
+	"net/http"
+	"strings"
+)
+
+// An Option configures the API key interceptor.
+type Option func(*interceptor)
+
+// WithHeader reads the keys from the header instead of X-Api-Key. The keys are
+// also read from the Authorization header with the ApiKey scheme.
+func WithHeader(name string) Option {
+	return func(i *interceptor) {
+		i.header = name
+	}
+}
+
+// WithScopes requires the keys used to call the procedures matching the
+// pattern to have all the scopes. The pattern is a procedure like
+// /acme.foo.v1.FooService/Bar or a prefix ending with /* like
+// /acme.foo.v1.FooService/*. Like for a Policy, only the scopes of the most
+// specific pattern matching a procedure are required: the procedure itself,
+// then the longest prefix.
+func WithScopes(pattern string, scopes ...string) Option {
+	return func(i *interceptor) {
+		i.scopes[pattern] = append(i.scopes[pattern], scopes...)
+	}
+}
+
+type interceptor struct {
+	store         KeyStore
+	header        string
+	scopes        map[string][]string
+	scopePatterns common.ProcedurePatterns
+}
+
+type keyKey struct{}
+
+// KeyFromContext returns the key of the call authenticated by the interceptor
+// returned by NewInterceptor.
+func KeyFromContext(ctx context.Context) (*Key, bool) {
+	key, ok := ctx.Value(keyKey{}).(*Key)
+	return key, ok
+}
+
+// NewInterceptor returns a server interceptor that authenticates the calls
+// with the API keys of the store. The key is added to the context, along with
+// a common.Principal.
+func NewInterceptor(store KeyStore, options ...Option) connect.Interceptor {
+	i := &interceptor{store: store, header: "X-Api-Key", scopes: make(map[string][]string)}
+	for _, option := range options {
+		option(i)
+	}
+	patterns := make([]string, 0, len(i.scopes))
+	for pattern := range i.scopes {
+		patterns = append(patterns, pattern)
+	}
+	i.scopePatterns = common.NewProcedurePatterns(patterns...)
+	return common.ContextSpecHeaderInterceptor(i.authenticate)
+}
+
+func (i *interceptor) authenticate(ctx context.Context, spec connect.Spec, header http.Header) (context.Context, error) {
+	rawKey := i.rawKey(header)
+	if rawKey == "" {
+		return ctx, connect.NewError(connect.CodeUnauthenticated, errors.New("missing API key"))
+	}
+	key, err := i.store.Lookup(ctx, HashKey(rawKey))
+	if err != nil {
+		return ctx, connect.NewError(connect.CodeUnavailable, fmt.Errorf("could not look up API key: %w", err))
+	}
+	if key == nil {
+		return ctx, connect.NewError(connect.CodeUnauthenticated, errors.New("unknown API key"))
+	}
+	if key.ExpiresAt != nil && !nowFunc().Before(*key.ExpiresAt) {
+		return ctx, connect.NewError(connect.CodeUnauthenticated, errors.New("expired API key"))
+	}
+	if len(key.Procedures) > 0 {
+		if _, ok := common.ProcedurePatterns(key.Procedures).Match(spec.Procedure); !ok {
+			return ctx, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("API key %s cannot call %s", key.ID, spec.Procedure))
+		}
+	}
+	if pattern, ok := i.scopePatterns.Match(spec.Procedure); ok {
+		for _, scope := range i.scopes[pattern] {
+			if !contains(key.Scopes, scope) {
+				return ctx, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("missing scope %s", scope))
+			}
+		}
+	}
+	subject := key.Subject
+	if subject == "" {
+		subject = key.ID
+	}
+	ctx = context.WithValue(ctx, keyKey{}, key)
+	return common.ContextWithPrincipal(ctx, &common.Principal{
+		Subject:    subject,
+		Scopes:     key.Scopes,
+		AuthMethod: common.AuthMethodAPIKey,
+	}), nil
+}
+
+// rawKey returns the key of the header, or of the Authorization header with
+// the ApiKey scheme.
+func (i *interceptor) rawKey(header http.Header) string {
+	if rawKey := strings.TrimSpace(header.Get(i.header)); rawKey != "" {
+		return rawKey
+	}
+	authorization := header.Get("Authorization")
+	if scheme, rawKey, ok := strings.Cut(authorization, " "); ok && strings.EqualFold(scheme, "ApiKey") {
+		return strings.TrimSpace(rawKey)
+	}
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
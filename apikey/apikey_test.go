@@ -0,0 +1,113 @@
+package apikey
+
+import (
+	"context"
+	"github.com/bufbuild/connect-go"
+	"github.com/hadrienk/connect-go-interceptors/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type msg struct{}
+
+type serverRequest struct {
+	*connect.Request[msg]
+	procedure string
+}
+
+func (s serverRequest) Spec() connect.Spec {
+	return connect.Spec{Procedure: s.procedure}
+}
+
+// call calls a handler wrapped by the interceptor with the header and returns
+// the context of the handler.
+func call(interceptor connect.Interceptor, procedure string, header http.Header) (context.Context, error) {
+	var handlerCtx context.Context
+	request := serverRequest{Request: connect.NewRequest(&msg{}), procedure: procedure}
+	for name, values := range header {
+		request.Header()[name] = values
+	}
+	_, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		handlerCtx = ctx
+		return connect.NewResponse(&msg{}), nil
+	})(context.Background(), request)
+	return handlerCtx, err
+}
+
+func keyHeader(key string) http.Header {
+	return http.Header{"X-Api-Key": []string{key}}
+}
+
+func TestNewInterceptor(t *testing.T) {
+	expiry := time.Now().Add(-time.Minute)
+	store := NewMemoryKeyStore(
+		Key{ID: "ci", Hash: HashKey("secret"), Subject: "ci-bot", Scopes: []string{"read", "write"}},
+		Key{ID: "expired", Hash: HashKey("old"), ExpiresAt: &expiry},
+		Key{ID: "reader", Hash: HashKey("reader"), Scopes: []string{"read"}, Procedures: []string{"/acme.foo.v1.FooService/*"}},
+	)
+	interceptor := NewInterceptor(store, WithScopes("/acme.foo.v1.FooService/Write", "write"))
+
+	t.Run("authenticates the key", func(t *testing.T) {
+		ctx, err := call(interceptor, "/acme.foo.v1.FooService/Write", keyHeader("secret"))
+		require.NoError(t, err)
+		key, ok := KeyFromContext(ctx)
+		require.True(t, ok)
+		assert.Equal(t, "ci", key.ID)
+		principal, ok := common.PrincipalFromContext(ctx)
+		require.True(t, ok)
+		assert.Equal(t, "ci-bot", principal.Subject)
+		assert.Equal(t, common.AuthMethodAPIKey, principal.AuthMethod)
+		assert.True(t, principal.HasScope("write"))
+	})
+	t.Run("reads the authorization header", func(t *testing.T) {
+		ctx, err := call(interceptor, "/acme.foo.v1.FooService/Read", http.Header{"Authorization": []string{"ApiKey reader"}})
+		require.NoError(t, err)
+		principal, _ := common.PrincipalFromContext(ctx)
+		assert.Equal(t, "reader", principal.Subject)
+	})
+	t.Run("rejects missing keys", func(t *testing.T) {
+		_, err := call(interceptor, "/acme.foo.v1.FooService/Read", http.Header{"Authorization": []string{"Bearer secret"}})
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+	t.Run("rejects unknown keys", func(t *testing.T) {
+		_, err := call(interceptor, "/acme.foo.v1.FooService/Read", keyHeader("guess"))
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+	t.Run("rejects expired keys", func(t *testing.T) {
+		_, err := call(interceptor, "/acme.foo.v1.FooService/Read", keyHeader("old"))
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+	t.Run("restricts the procedures", func(t *testing.T) {
+		_, err := call(interceptor, "/acme.bar.v1.BarService/Read", keyHeader("reader"))
+		assert.Equal(t, connect.CodePermissionDenied, connect.CodeOf(err))
+	})
+	t.Run("requires the scopes", func(t *testing.T) {
+		_, err := call(interceptor, "/acme.foo.v1.FooService/Write", keyHeader("reader"))
+		assert.Equal(t, connect.CodePermissionDenied, connect.CodeOf(err))
+	})
+}
+
+func TestWithHeader(t *testing.T) {
+	interceptor := NewInterceptor(NewMemoryKeyStore(Key{ID: "ci", Hash: HashKey("secret")}), WithHeader("Api-Token"))
+
+	_, err := call(interceptor, "/acme.foo.v1.FooService/Read", http.Header{"Api-Token": []string{"secret"}})
+	assert.NoError(t, err)
+	_, err = call(interceptor, "/acme.foo.v1.FooService/Read", keyHeader("secret"))
+	assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+}
+
+func TestWithScopes(t *testing.T) {
+	store := NewMemoryKeyStore(Key{ID: "ci", Hash: HashKey("secret"), Scopes: []string{"read"}})
+	interceptor := NewInterceptor(store,
+		WithScopes("/acme.foo.v1.FooService/*", "write"),
+		WithScopes("/acme.foo.v1.FooService/Read", "read"),
+	)
+
+	_, err := call(interceptor, "/acme.foo.v1.FooService/Read", keyHeader("secret"))
+	assert.NoError(t, err, "only the most specific pattern applies")
+	_, err = call(interceptor, "/acme.foo.v1.FooService/Write", keyHeader("secret"))
+	assert.Equal(t, connect.CodePermissionDenied, connect.CodeOf(err))
+}
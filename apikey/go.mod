@@ -0,0 +1,15 @@
+module github.com/hadrienk/connect-go-interceptors/apikey
+
+go 1.20
+
+require (
+	github.com/bufbuild/connect-go v1.5.2
+	github.com/stretchr/testify v1.8.4
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	google.golang.org/protobuf v1.28.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
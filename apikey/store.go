@@ -0,0 +1,144 @@
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// A Key is an API key registered in a KeyStore. Only the hash of the key is
+// stored.
+type Key struct {
+	ID string `json:"id"`
+	// Hash is the hex encoded SHA-256 hash of the key, see HashKey.
+	Hash string `json:"hash"`
+	// Subject is the owner of the key. Defaults to the ID in the principal.
+	Subject string   `json:"subject,omitempty"`
+	Scopes  []string `json:"scopes,omitempty"`
+	// ExpiresAt is when the key expires, if not nil.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Procedures restrict the key to the procedures matching the patterns if
+	// not empty. The patterns are procedures like /acme.foo.v1.FooService/Bar or
+	// prefixes ending with /* like /acme.foo.v1.FooService/*.
+	Procedures []string `json:"procedures,omitempty"`
+}
+
+// HashKey returns the hash of the key to register in a KeyStore.
+func HashKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// A KeyStore looks up the keys by hash.
+type KeyStore interface {
+	// Lookup returns the key with the hash, or nil if there is none. The hash
+	// must be compared in constant time.
+	Lookup(ctx context.Context, hash string) (*Key, error)
+}
+
+// lookup returns a copy of the key with the hash, comparing the hash with all
+// the keys in constant time.
+func lookup(keys []Key, hash string) *Key {
+	var found *Key
+	for i := range keys {
+		if subtle.ConstantTimeCompare([]byte(keys[i].Hash), []byte(hash)) == 1 {
+			key := keys[i]
+			found = &key
+		}
+	}
+	if found != nil {
+		found.Scopes = append([]string(nil), found.Scopes...)
+		found.Procedures = append([]string(nil), found.Procedures...)
+		if found.ExpiresAt != nil {
+			expiresAt := *found.ExpiresAt
+			found.ExpiresAt = &expiresAt
+		}
+	}
+	return found
+}
+
+// A MemoryKeyStore is a KeyStore holding the keys in memory.
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys []Key
+}
+
+// NewMemoryKeyStore returns a MemoryKeyStore with the keys.
+func NewMemoryKeyStore(keys ...Key) *MemoryKeyStore {
+	return &MemoryKeyStore{keys: keys}
+}
+
+// SetKeys replaces the keys of the store.
+func (m *MemoryKeyStore) SetKeys(keys ...Key) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys = keys
+}
+
+func (m *MemoryKeyStore) Lookup(_ context.Context, hash string) (*Key, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return lookup(m.keys, hash), nil
+}
+
+var nowFunc = time.Now
+
+// A FileKeyStore is a KeyStore reading the keys from a JSON file holding an
+// array of Key. The file is reloaded when it changes.
+type FileKeyStore struct {
+	path           string
+	reloadInterval time.Duration
+	mu             sync.Mutex
+	keys           []Key
+	modTime        time.Time
+	size           int64
+	checked        time.Time
+}
+
+// NewFileKeyStore returns a FileKeyStore reading the keys from the file. The
+// file is checked for changes at most once per reload interval during the
+// lookups. The previous keys are kept if the file cannot be reloaded.
+func NewFileKeyStore(path string, reloadInterval time.Duration) (*FileKeyStore, error) {
+	store := &FileKeyStore{path: path, reloadInterval: reloadInterval}
+	if err := store.reload(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (f *FileKeyStore) Lookup(_ context.Context, hash string) (*Key, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if now := nowFunc(); now.Sub(f.checked) >= f.reloadInterval {
+		_ = f.reload()
+	}
+	return lookup(f.keys, hash), nil
+}
+
+// reload reads the file if it changed since it was last read.
+func (f *FileKeyStore) reload() error {
+	f.checked = nowFunc()
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return nil
+	}
+	bytes, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	var keys []Key
+	if err := json.Unmarshal(bytes, &keys); err != nil {
+		return fmt.Errorf("malformed key file %s: %w", f.path, err)
+	}
+	f.keys, f.modTime, f.size = keys, info.ModTime(), info.Size()
+	return nil
+}
@@ -0,0 +1,63 @@
+package apikey
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemoryKeyStore(t *testing.T) {
+	store := NewMemoryKeyStore(Key{ID: "a", Hash: HashKey("a")})
+
+	key, err := store.Lookup(context.Background(), HashKey("a"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", key.ID)
+	key.ID = "modified"
+	key, err = store.Lookup(context.Background(), HashKey("a"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", key.ID, "modified the stored key")
+
+	store.SetKeys(Key{ID: "b", Hash: HashKey("b")})
+	key, err = store.Lookup(context.Background(), HashKey("a"))
+	require.NoError(t, err)
+	assert.Nil(t, key)
+}
+
+func TestFileKeyStore(t *testing.T) {
+	now := time.Now()
+	nowFunc = func() time.Time { return now }
+	defer func() { nowFunc = time.Now }()
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"id": "a", "hash": "`+HashKey("a")+`", "scopes": ["read"]}]`), 0o600))
+	store, err := NewFileKeyStore(path, time.Minute)
+	require.NoError(t, err)
+
+	key, err := store.Lookup(context.Background(), HashKey("a"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"read"}, key.Scopes)
+
+	require.NoError(t, os.WriteFile(path, []byte(`[{"id": "b", "hash": "`+HashKey("b")+`", "expires_at": "2030-01-01T00:00:00Z"}]`), 0o600))
+	key, err = store.Lookup(context.Background(), HashKey("b"))
+	require.NoError(t, err)
+	assert.Nil(t, key, "reloaded before the interval")
+
+	now = now.Add(time.Minute)
+	key, err = store.Lookup(context.Background(), HashKey("b"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", key.ID)
+	assert.Equal(t, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), *key.ExpiresAt)
+
+	require.NoError(t, os.WriteFile(path, []byte(`not json`), 0o600))
+	now = now.Add(time.Minute)
+	key, err = store.Lookup(context.Background(), HashKey("b"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", key.ID, "kept the previous keys")
+
+	_, err = NewFileKeyStore(path, time.Minute)
+	assert.Error(t, err)
+}
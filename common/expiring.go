@@ -0,0 +1,46 @@
+package common
+
+import (
+	"sync"
+	"time"
+)
+
+// An ExpiringSet remembers values until they expire, e.g. the nonces of signed
+// requests to reject the replayed ones. The expired values are removed once
+// the set doubled in size since the last sweep.
+type ExpiringSet struct {
+	mu      sync.Mutex
+	expiry  map[string]time.Time
+	sweepAt int
+}
+
+// minSweep is the number of values stored before the expired ones are removed.
+const minSweep = 1024
+
+// NewExpiringSet returns an empty ExpiringSet.
+func NewExpiringSet() *ExpiringSet {
+	return &ExpiringSet{expiry: make(map[string]time.Time), sweepAt: minSweep}
+}
+
+// Add stores the value until the expiry and returns true, or returns false if
+// the value is already stored and not expired at now.
+func (s *ExpiringSet) Add(value string, now, expiry time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if valueExpiry, ok := s.expiry[value]; ok && now.Before(valueExpiry) {
+		return false
+	}
+	if len(s.expiry) >= s.sweepAt {
+		for storedValue, storedExpiry := range s.expiry {
+			if !now.Before(storedExpiry) {
+				delete(s.expiry, storedValue)
+			}
+		}
+		s.sweepAt = 2 * len(s.expiry)
+		if s.sweepAt < minSweep {
+			s.sweepAt = minSweep
+		}
+	}
+	s.expiry[value] = expiry
+	return true
+}
@@ -0,0 +1,28 @@
+package common
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestExpiringSet_Add(t *testing.T) {
+	now := time.Now()
+	set := NewExpiringSet()
+
+	assert.True(t, set.Add("a", now, now.Add(time.Minute)))
+	assert.False(t, set.Add("a", now, now.Add(time.Minute)))
+	assert.True(t, set.Add("a", now.Add(time.Minute), now.Add(2*time.Minute)), "expired value")
+}
+
+func TestExpiringSet_sweep(t *testing.T) {
+	now := time.Now()
+	set := NewExpiringSet()
+	for i := 0; i < minSweep; i++ {
+		set.Add(strconv.Itoa(i), now, now.Add(time.Minute))
+	}
+	set.Add("last", now.Add(time.Minute), now.Add(2*time.Minute))
+	assert.Len(t, set.expiry, 1)
+	assert.Equal(t, minSweep, set.sweepAt)
+}
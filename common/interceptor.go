@@ -59,3 +59,31 @@ func (chi ContextHeaderInterceptor) WrapStreamingHandler(next connect.StreamingH
 		return next(ctx, conn)
 	}
 }
+
+// ContextSpecHeaderInterceptor is a server interceptor that fails all call with the error it returns. It gets both
+// the connect.Spec and the request header of the calls.
+type ContextSpecHeaderInterceptor func(ctx context.Context, spec connect.Spec, header http.Header) (context.Context, error)
+
+func (cshi ContextSpecHeaderInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		ctx, err := cshi(ctx, request.Spec(), request.Header())
+		if err != nil {
+			return nil, err
+		}
+		return next(ctx, request)
+	}
+}
+
+func (cshi ContextSpecHeaderInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (cshi ContextSpecHeaderInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, err := cshi(ctx, conn.Spec(), conn.RequestHeader())
+		if err != nil {
+			return err
+		}
+		return next(ctx, conn)
+	}
+}
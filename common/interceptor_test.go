@@ -155,6 +155,82 @@ func TestContextSpecInterceptor_WrapUnary(t *testing.T) {
 	})
 }
 
+func TestContextSpecHeaderInterceptor_WrapStreamingClient(t *testing.T) {
+	interceptor := ContextSpecHeaderInterceptor(func(ctx context.Context, spec connect.Spec, header http.Header) (context.Context, error) {
+		t.Fail()
+		return nil, nil
+	})
+	reached := false
+	next := connect.StreamingClientFunc(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		reached = true
+		return nil
+	})
+	interceptor.WrapStreamingClient(next)(nil, connect.Spec{})
+	assert.True(t, reached)
+}
+
+func TestContextSpecHeaderInterceptor_WrapStreamingHandler(t *testing.T) {
+	t.Run("propagate any error", func(t *testing.T) {
+		expectedError := errors.New("error")
+		interceptor := ContextSpecHeaderInterceptor(func(ctx context.Context, spec connect.Spec, header http.Header) (context.Context, error) {
+			return nil, expectedError
+		})
+		err := interceptor.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+			t.Fail()
+			return nil
+		})(context.Background(), noOpConn{})
+		assert.ErrorIs(t, err, expectedError)
+	})
+
+	t.Run("passes context, spec and header", func(t *testing.T) {
+		expectedSpec := connect.Spec{
+			Procedure: "Foo",
+		}
+		expectedHeader := http.Header{
+			"Foo": {"Bar", "Baz"},
+		}
+		interceptor := ContextSpecHeaderInterceptor(func(ctx context.Context, spec connect.Spec, header http.Header) (context.Context, error) {
+			assert.Equal(t, expectedSpec, spec)
+			assert.Equal(t, expectedHeader, header)
+			return context.WithValue(ctx, "test", "value"), nil
+		})
+		err := interceptor.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+			assert.Equal(t, "value", ctx.Value("test"))
+			return nil
+		})(context.Background(), noOpConn{spec: expectedSpec, reqHeader: expectedHeader})
+		assert.NoError(t, err)
+	})
+}
+
+func TestContextSpecHeaderInterceptor_WrapUnary(t *testing.T) {
+	t.Run("propagate any error", func(t *testing.T) {
+		expectedError := errors.New("error")
+		interceptor := ContextSpecHeaderInterceptor(func(ctx context.Context, spec connect.Spec, header http.Header) (context.Context, error) {
+			return nil, expectedError
+		})
+		_, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			t.Fail()
+			return nil, nil
+		})(context.Background(), connect.NewRequest(&map[string]string{}))
+		assert.ErrorIs(t, err, expectedError)
+	})
+
+	t.Run("passes context, spec and header", func(t *testing.T) {
+		request := connect.NewRequest(&map[string]string{})
+		request.Header().Add("Foo", "Bar")
+		interceptor := ContextSpecHeaderInterceptor(func(ctx context.Context, spec connect.Spec, header http.Header) (context.Context, error) {
+			assert.Equal(t, request.Spec(), spec)
+			assert.Equal(t, request.Header(), header)
+			return context.WithValue(ctx, "test", "value"), nil
+		})
+		_, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			assert.Equal(t, "value", ctx.Value("test"))
+			return nil, nil
+		})(context.Background(), request)
+		assert.NoError(t, err)
+	})
+}
+
 type noOpConn struct {
 	receive    func(msg any) error
 	send       func(msg any) error
@@ -0,0 +1,67 @@
+package common
+
+import (
+	"github.com/bufbuild/connect-go"
+	"strconv"
+)
+
+// CodeString returns the gRPC name of the code of the error, e.g.
+// PermissionDenied, or OK if the error is nil.
+func CodeString(err error) string {
+	if err == nil {
+		return "OK"
+	}
+	code := connect.CodeOf(err)
+	switch code {
+	case connect.CodeCanceled:
+		return "Canceled"
+	case connect.CodeUnknown:
+		return "Unknown"
+	case connect.CodeInvalidArgument:
+		return "InvalidArgument"
+	case connect.CodeDeadlineExceeded:
+		return "DeadlineExceeded"
+	case connect.CodeNotFound:
+		return "NotFound"
+	case connect.CodeAlreadyExists:
+		return "AlreadyExists"
+	case connect.CodePermissionDenied:
+		return "PermissionDenied"
+	case connect.CodeResourceExhausted:
+		return "ResourceExhausted"
+	case connect.CodeFailedPrecondition:
+		return "FailedPrecondition"
+	case connect.CodeAborted:
+		return "Aborted"
+	case connect.CodeOutOfRange:
+		return "OutOfRange"
+	case connect.CodeUnimplemented:
+		return "Unimplemented"
+	case connect.CodeInternal:
+		return "Internal"
+	case connect.CodeUnavailable:
+		return "Unavailable"
+	case connect.CodeDataLoss:
+		return "DataLoss"
+	case connect.CodeUnauthenticated:
+		return "Unauthenticated"
+	default:
+		return "Code(" + strconv.FormatInt(int64(code), 10) + ")"
+	}
+}
+
+// StreamTypeString returns the name of the stream type, e.g. client_stream.
+func StreamTypeString(streamType connect.StreamType) string {
+	switch {
+	case streamType == connect.StreamTypeUnary:
+		return "unary"
+	case streamType == connect.StreamTypeClient:
+		return "client_stream"
+	case streamType == connect.StreamTypeServer:
+		return "server_stream"
+	case streamType == connect.StreamTypeBidi:
+		return "bidi_stream"
+	default:
+		return "unknown"
+	}
+}
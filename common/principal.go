@@ -0,0 +1,58 @@
+package common
+
+import "context"
+
+// An AuthMethod is the way a Principal was authenticated.
+type AuthMethod string
+
+const (
+	AuthMethodOIDC   AuthMethod = "oidc"
+	AuthMethodOAuth2 AuthMethod = "oauth2"
+	AuthMethodAPIKey AuthMethod = "api_key"
+	AuthMethodMTLS   AuthMethod = "mtls"
+	AuthMethodBasic  AuthMethod = "basic"
+)
+
+// A Principal is the caller authenticated by one of the authentication
+// interceptors. Handlers get it with PrincipalFromContext regardless of the
+// way it was authenticated.
+type Principal struct {
+	Subject    string
+	Issuer     string
+	Roles      []string
+	Scopes     []string
+	Claims     map[string]any
+	AuthMethod AuthMethod
+}
+
+// HasRole returns true if the principal has the role.
+func (p *Principal) HasRole(role string) bool {
+	return contains(p.Roles, role)
+}
+
+// HasScope returns true if the principal has the scope.
+func (p *Principal) HasScope(scope string) bool {
+	return contains(p.Scopes, scope)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of the context with the principal.
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal of the call.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
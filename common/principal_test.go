@@ -0,0 +1,25 @@
+package common
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrincipalFromContext(t *testing.T) {
+	_, ok := PrincipalFromContext(context.Background())
+	assert.False(t, ok)
+
+	expected := &Principal{Subject: "subject", AuthMethod: AuthMethodOIDC}
+	principal, ok := PrincipalFromContext(ContextWithPrincipal(context.Background(), expected))
+	assert.True(t, ok)
+	assert.Same(t, expected, principal)
+}
+
+func TestPrincipal_HasRole(t *testing.T) {
+	principal := &Principal{Roles: []string{"admin"}, Scopes: []string{"read"}}
+	assert.True(t, principal.HasRole("admin"))
+	assert.False(t, principal.HasRole("read"))
+	assert.True(t, principal.HasScope("read"))
+	assert.False(t, principal.HasScope("admin"))
+}
@@ -0,0 +1,59 @@
+package common
+
+import (
+	"sort"
+	"strings"
+)
+
+// SplitProcedure returns the service and the method of a procedure like
+// /acme.foo.v1.FooService/Bar, or unknown for both if it is malformed.
+func SplitProcedure(procedure string) (string, string) {
+	procedure = strings.TrimPrefix(procedure, "/") // remove leading slash
+	if i := strings.Index(procedure, "/"); i >= 0 {
+		return procedure[:i], procedure[i+1:]
+	}
+	return "unknown", "unknown"
+}
+
+// MatchesPattern returns true if the pattern is the value itself, or a prefix
+// of the value ending with /*.
+func MatchesPattern(pattern, value string) bool {
+	if isPrefix(pattern) {
+		return strings.HasPrefix(value, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == value
+}
+
+func isPrefix(pattern string) bool {
+	return strings.HasSuffix(pattern, "/*")
+}
+
+// ProcedurePatterns match procedures. The patterns are procedures like
+// /acme.foo.v1.FooService/Bar or prefixes ending with /* like
+// /acme.foo.v1.FooService/*, and /* for all the procedures.
+type ProcedurePatterns []string
+
+// NewProcedurePatterns returns the patterns ordered from the most specific to
+// the least specific: the procedures, then the prefixes from the longest.
+func NewProcedurePatterns(patterns ...string) ProcedurePatterns {
+	sorted := append(ProcedurePatterns{}, patterns...)
+	sort.Slice(sorted, func(i, j int) bool {
+		iPrefix, jPrefix := isPrefix(sorted[i]), isPrefix(sorted[j])
+		if iPrefix != jPrefix {
+			return jPrefix
+		}
+		return len(sorted[i]) > len(sorted[j])
+	})
+	return sorted
+}
+
+// Match returns the first pattern matching the procedure, which is the most
+// specific one if the patterns were returned by NewProcedurePatterns.
+func (p ProcedurePatterns) Match(procedure string) (string, bool) {
+	for _, pattern := range p {
+		if MatchesPattern(pattern, procedure) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
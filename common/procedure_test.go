@@ -0,0 +1,64 @@
+package common
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSplitProcedure(t *testing.T) {
+	type args struct {
+		procedure string
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantService string
+		wantMethod  string
+	}{
+		{"correct package & method", args{"foo.bar/Baz"}, "foo.bar", "Baz"},
+		{"empty method name", args{"foo.bar/"}, "foo.bar", ""},
+		{"empty package", args{"/Baz"}, "unknown", "unknown"},
+		{"only package", args{"foo.bar"}, "unknown", "unknown"},
+		{"only method", args{"Baz"}, "unknown", "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, got1 := SplitProcedure(tt.args.procedure)
+			assert.Equalf(t, tt.wantService, got, "SplitProcedure(%v)", tt.args.procedure)
+			assert.Equalf(t, tt.wantMethod, got1, "SplitProcedure(%v)", tt.args.procedure)
+		})
+	}
+}
+
+func TestMatchesPattern(t *testing.T) {
+	assert.True(t, MatchesPattern("/acme.foo.v1.FooService/Bar", "/acme.foo.v1.FooService/Bar"))
+	assert.False(t, MatchesPattern("/acme.foo.v1.FooService/Bar", "/acme.foo.v1.FooService/BarBaz"))
+	assert.True(t, MatchesPattern("/acme.foo.v1.FooService/*", "/acme.foo.v1.FooService/Bar"))
+	assert.False(t, MatchesPattern("/acme.foo.v1.FooService/*", "/acme.foo.v1.FooServiceV2/Bar"))
+	assert.True(t, MatchesPattern("/*", "/acme.foo.v1.FooService/Bar"))
+}
+
+func TestProcedurePatterns_Match(t *testing.T) {
+	patterns := NewProcedurePatterns("/*", "/acme.foo.v1.FooService/*", "/acme.foo.v1.FooService/Bar")
+	assert.Equal(t, ProcedurePatterns{"/acme.foo.v1.FooService/Bar", "/acme.foo.v1.FooService/*", "/*"}, patterns)
+
+	tests := []struct {
+		procedure string
+		want      string
+		wantOk    bool
+	}{
+		{"/acme.foo.v1.FooService/Bar", "/acme.foo.v1.FooService/Bar", true},
+		{"/acme.foo.v1.FooService/Baz", "/acme.foo.v1.FooService/*", true},
+		{"/acme.bar.v1.BarService/Baz", "/*", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.procedure, func(t *testing.T) {
+			got, ok := patterns.Match(tt.procedure)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, ok := NewProcedurePatterns("/acme.foo.v1.FooService/*").Match("/acme.bar.v1.BarService/Baz")
+	assert.False(t, ok)
+}
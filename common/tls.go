@@ -0,0 +1,29 @@
+package common
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+)
+
+type tlsStateKey struct{}
+
+// NewTLSStateHandler returns a handler that adds the TLS state of the
+// connection of the requests to their context before calling the handler, so
+// that the interceptors can reach the certificates of the clients with
+// TLSStateFromContext. It wraps the handlers of connect.
+func NewTLSStateHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil {
+			r = r.WithContext(context.WithValue(r.Context(), tlsStateKey{}, r.TLS))
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// TLSStateFromContext returns the TLS state of the connection added by the
+// handler returned by NewTLSStateHandler.
+func TLSStateFromContext(ctx context.Context) (*tls.ConnectionState, bool) {
+	state, ok := ctx.Value(tlsStateKey{}).(*tls.ConnectionState)
+	return state, ok
+}
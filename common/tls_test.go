@@ -0,0 +1,32 @@
+package common
+
+import (
+	"crypto/tls"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewTLSStateHandler(t *testing.T) {
+	t.Run("adds the TLS state", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/foo.v1.FooService/Bar", nil)
+		request.TLS = &tls.ConnectionState{ServerName: "example.com"}
+		NewTLSStateHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			state, ok := TLSStateFromContext(r.Context())
+			assert.True(t, ok)
+			assert.Same(t, request.TLS, state)
+		})).ServeHTTP(httptest.NewRecorder(), request)
+	})
+
+	t.Run("ignores plain connections", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/foo.v1.FooService/Bar", nil)
+		reached := false
+		NewTLSStateHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reached = true
+			_, ok := TLSStateFromContext(r.Context())
+			assert.False(t, ok)
+		})).ServeHTTP(httptest.NewRecorder(), request)
+		assert.True(t, reached)
+	})
+}
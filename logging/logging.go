@@ -0,0 +1,146 @@
+package logging
+
+import (
+	"context"
+	"github.com/bufbuild/connect-go"
+	"github.com/hadrienk/connect-go-interceptors/common"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+var sinceFunc = time.Since
+var nowFunc = time.Now
+
+type interceptor struct {
+	logger          *slog.Logger
+	sampler         Sampler
+	levels          func(err error) slog.Level
+	requestHeaders  []string
+	responseHeaders []string
+}
+
+// NewInterceptor returns a connect.Interceptor that emits one structured record
+// per RPC once it is finished, on both handlers and clients.
+func NewInterceptor(options ...Option) connect.Interceptor {
+	i := &interceptor{
+		levels: DefaultLevels,
+	}
+	for _, option := range options {
+		option(i)
+	}
+	if i.logger == nil {
+		i.logger = slog.Default()
+	}
+	return i
+}
+
+// call accumulates what is known about an RPC until it is logged.
+type call struct {
+	spec     connect.Spec
+	peer     connect.Peer
+	start    time.Time
+	received atomic.Int64
+	sent     atomic.Int64
+}
+
+func newCall(spec connect.Spec, peer connect.Peer) *call {
+	return &call{spec: spec, peer: peer, start: nowFunc()}
+}
+
+func (i *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		c := newCall(request.Spec(), request.Peer())
+		if c.spec.IsClient {
+			c.sent.Add(1)
+		} else {
+			c.received.Add(1)
+		}
+		response, err := next(ctx, request)
+		var responseHeader http.Header
+		if err == nil {
+			if c.spec.IsClient {
+				c.received.Add(1)
+			} else {
+				c.sent.Add(1)
+			}
+			responseHeader = response.Header()
+		}
+		i.log(ctx, c, request.Header(), responseHeader, err)
+		return response, err
+	}
+}
+
+func (i *interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		return &loggingClient{
+			StreamingClientConn: conn,
+			interceptor:         i,
+			ctx:                 ctx,
+			call:                newCall(spec, conn.Peer()),
+		}
+	}
+}
+
+func (i *interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		c := newCall(conn.Spec(), conn.Peer())
+		err := next(ctx, &loggingHandler{
+			StreamingHandlerConn: conn,
+			call:                 c,
+		})
+		i.log(ctx, c, conn.RequestHeader(), conn.ResponseHeader(), err)
+		return err
+	}
+}
+
+func (i *interceptor) log(ctx context.Context, c *call, requestHeader, responseHeader http.Header, err error) {
+	level := i.levels(err)
+	if !i.logger.Enabled(ctx, level) {
+		return
+	}
+	if i.sampler != nil && !i.sampler(ctx, c.spec, err) {
+		return
+	}
+	kind := "server"
+	if c.spec.IsClient {
+		kind = "client"
+	}
+	attrs := []slog.Attr{
+		slog.String("kind", kind),
+		slog.String("procedure", c.spec.Procedure),
+		slog.String("stream_type", common.StreamTypeString(c.spec.StreamType)),
+		slog.String("peer_addr", c.peer.Addr),
+		slog.String("protocol", c.peer.Protocol),
+		slog.Duration("duration", sinceFunc(c.start)),
+		slog.String("code", common.CodeString(err)),
+		slog.Int64("msg_received", c.received.Load()),
+		slog.Int64("msg_sent", c.sent.Load()),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	if group, ok := headerGroup("request_header", requestHeader, i.requestHeaders); ok {
+		attrs = append(attrs, group)
+	}
+	if group, ok := headerGroup("response_header", responseHeader, i.responseHeaders); ok {
+		attrs = append(attrs, group)
+	}
+	i.logger.LogAttrs(ctx, level, "finished call", attrs...)
+}
+
+// headerGroup returns the allowed headers that are present as a group.
+func headerGroup(key string, header http.Header, allowed []string) (slog.Attr, bool) {
+	var attrs []any
+	for _, name := range allowed {
+		if values := header.Values(name); len(values) > 0 {
+			attrs = append(attrs, slog.Any(name, values))
+		}
+	}
+	if len(attrs) == 0 {
+		return slog.Attr{}, false
+	}
+	return slog.Group(key, attrs...), true
+}
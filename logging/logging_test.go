@@ -0,0 +1,269 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/bufbuild/connect-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type msg struct {
+}
+
+func newLogger(buffer *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(buffer, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+func records(t *testing.T, buffer *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestInterceptor_WrapUnary(t *testing.T) {
+	defer func() {
+		sinceFunc = time.Since
+	}()
+	sinceFunc = func(_ time.Time) time.Duration {
+		return time.Second
+	}
+
+	t.Run("logs successful calls", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		interceptor := NewInterceptor(
+			WithLogger(newLogger(buffer)),
+			WithRequestHeaders("x-request-id", "x-missing"),
+			WithResponseHeaders("X-Response"),
+		)
+		request := connect.NewRequest(&msg{})
+		request.Header().Set("X-Request-Id", "1234")
+		request.Header().Set("Authorization", "secret")
+
+		_, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			response := connect.NewResponse(&msg{})
+			response.Header().Set("X-Response", "foo")
+			return response, nil
+		})(context.Background(), request)
+		assert.NoError(t, err)
+
+		assert.Equal(t, []map[string]any{{
+			"level":           "INFO",
+			"msg":             "finished call",
+			"kind":            "server",
+			"procedure":       "",
+			"stream_type":     "unary",
+			"peer_addr":       "",
+			"protocol":        "",
+			"duration":        float64(time.Second),
+			"code":            "OK",
+			"msg_received":    float64(1),
+			"msg_sent":        float64(1),
+			"request_header":  map[string]any{"X-Request-Id": []any{"1234"}},
+			"response_header": map[string]any{"X-Response": []any{"foo"}},
+		}}, records(t, buffer))
+	})
+
+	t.Run("maps codes to levels", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		interceptor := NewInterceptor(WithLogger(newLogger(buffer)))
+		for _, code := range []connect.Code{connect.CodeNotFound, connect.CodeUnavailable, connect.CodeInternal} {
+			_, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+				return nil, connect.NewError(code, errors.New("failed"))
+			})(context.Background(), connect.NewRequest(&msg{}))
+			assert.Error(t, err)
+		}
+
+		logged := records(t, buffer)
+		require.Len(t, logged, 3)
+		assert.Equal(t, "INFO", logged[0]["level"])
+		assert.Equal(t, "NotFound", logged[0]["code"])
+		assert.Equal(t, "not_found: failed", logged[0]["error"])
+		assert.Equal(t, float64(0), logged[0]["msg_sent"])
+		assert.Equal(t, "WARN", logged[1]["level"])
+		assert.Equal(t, "ERROR", logged[2]["level"])
+	})
+
+	t.Run("supports custom levels", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		interceptor := NewInterceptor(WithLogger(newLogger(buffer)), WithLevels(func(err error) slog.Level {
+			return slog.LevelDebug
+		}))
+		_, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			return connect.NewResponse(&msg{}), nil
+		})(context.Background(), connect.NewRequest(&msg{}))
+		assert.NoError(t, err)
+		assert.Empty(t, buffer.String())
+	})
+
+	t.Run("samples calls", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		interceptor := NewInterceptor(WithLogger(newLogger(buffer)), WithSampler(SampleRate(0)))
+		_, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			return connect.NewResponse(&msg{}), nil
+		})(context.Background(), connect.NewRequest(&msg{}))
+		assert.NoError(t, err)
+		assert.Empty(t, buffer.String())
+
+		_, err = interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			return nil, connect.NewError(connect.CodeInternal, errors.New("failed"))
+		})(context.Background(), connect.NewRequest(&msg{}))
+		assert.Error(t, err)
+		assert.Len(t, records(t, buffer), 1)
+	})
+}
+
+func TestInterceptor_WrapStreamingHandler(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	interceptor := NewInterceptor(WithLogger(newLogger(buffer)))
+	conn := noOpConn{
+		spec: connect.Spec{Procedure: "/foo.v1.FooService/Bar", StreamType: connect.StreamTypeBidi},
+		peer: connect.Peer{Addr: "127.0.0.1:1234", Protocol: connect.ProtocolGRPC},
+	}
+
+	err := interceptor.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		_ = conn.Receive(&msg{})
+		_ = conn.Receive(&msg{})
+		_ = conn.Send(&msg{})
+		return nil
+	})(context.Background(), conn)
+	assert.NoError(t, err)
+
+	logged := records(t, buffer)
+	require.Len(t, logged, 1)
+	assert.Equal(t, "/foo.v1.FooService/Bar", logged[0]["procedure"])
+	assert.Equal(t, "bidi_stream", logged[0]["stream_type"])
+	assert.Equal(t, "127.0.0.1:1234", logged[0]["peer_addr"])
+	assert.Equal(t, "grpc", logged[0]["protocol"])
+	assert.Equal(t, float64(2), logged[0]["msg_received"])
+	assert.Equal(t, float64(1), logged[0]["msg_sent"])
+}
+
+func TestInterceptor_WrapStreamingClient(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	interceptor := NewInterceptor(WithLogger(newLogger(buffer)))
+	received := 0
+	conn := interceptor.WrapStreamingClient(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		return noOpClientConn{
+			spec: spec,
+			receive: func(msg any) error {
+				received++
+				if received > 3 {
+					return io.EOF
+				}
+				return nil
+			},
+		}
+	})(context.Background(), connect.Spec{StreamType: connect.StreamTypeServer, IsClient: true})
+
+	assert.NoError(t, conn.Send(&msg{}))
+	for conn.Receive(&msg{}) == nil {
+	}
+	assert.NoError(t, conn.CloseResponse())
+
+	logged := records(t, buffer)
+	require.Len(t, logged, 1)
+	assert.Equal(t, "client", logged[0]["kind"])
+	assert.Equal(t, "OK", logged[0]["code"])
+	assert.Equal(t, float64(3), logged[0]["msg_received"])
+	assert.Equal(t, float64(1), logged[0]["msg_sent"])
+}
+
+type noOpConn struct {
+	spec connect.Spec
+	peer connect.Peer
+}
+
+func (n noOpConn) Spec() connect.Spec {
+	return n.spec
+}
+
+func (n noOpConn) Peer() connect.Peer {
+	return n.peer
+}
+
+func (n noOpConn) Receive(msg any) error {
+	return nil
+}
+
+func (n noOpConn) RequestHeader() http.Header {
+	return http.Header{}
+}
+
+func (n noOpConn) Send(msg any) error {
+	return nil
+}
+
+func (n noOpConn) ResponseHeader() http.Header {
+	return http.Header{}
+}
+
+func (n noOpConn) ResponseTrailer() http.Header {
+	return http.Header{}
+}
+
+type noOpClientConn struct {
+	receive func(msg any) error
+	spec    connect.Spec
+}
+
+func (n noOpClientConn) Spec() connect.Spec {
+	return n.spec
+}
+
+func (n noOpClientConn) Peer() connect.Peer {
+	return connect.Peer{}
+}
+
+func (n noOpClientConn) Send(msg any) error {
+	return nil
+}
+
+func (n noOpClientConn) RequestHeader() http.Header {
+	return http.Header{}
+}
+
+func (n noOpClientConn) CloseRequest() error {
+	return nil
+}
+
+func (n noOpClientConn) Receive(msg any) error {
+	if n.receive != nil {
+		return n.receive(msg)
+	}
+	return nil
+}
+
+func (n noOpClientConn) ResponseHeader() http.Header {
+	return http.Header{}
+}
+
+func (n noOpClientConn) ResponseTrailer() http.Header {
+	return http.Header{}
+}
+
+func (n noOpClientConn) CloseResponse() error {
+	return nil
+}
@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"context"
+	"github.com/bufbuild/connect-go"
+	"log/slog"
+	"math/rand"
+	"net/http"
+)
+
+// An Option configures the logging interceptor.
+type Option func(*interceptor)
+
+// A Sampler decides whether the call should be logged. err is the error the
+// call ended with.
+type Sampler func(ctx context.Context, spec connect.Spec, err error) bool
+
+// WithLogger sets the logger used to emit the records. Defaults to slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(i *interceptor) {
+		i.logger = logger
+	}
+}
+
+// WithSampler only logs the calls for which the sampler returns true.
+func WithSampler(sampler Sampler) Option {
+	return func(i *interceptor) {
+		i.sampler = sampler
+	}
+}
+
+// SampleRate returns a Sampler that keeps the given fraction of the successful
+// calls. Failed calls are always logged.
+func SampleRate(rate float64) Sampler {
+	return func(ctx context.Context, spec connect.Spec, err error) bool {
+		return err != nil || rand.Float64() < rate
+	}
+}
+
+// WithLevels sets the function that maps the error of the call to the level of
+// the record. A nil error means the call succeeded. Defaults to DefaultLevels.
+func WithLevels(levels func(err error) slog.Level) Option {
+	return func(i *interceptor) {
+		i.levels = levels
+	}
+}
+
+// DefaultLevels logs successful calls and client errors at info level, errors
+// that may need attention at warn level and server failures at error level.
+func DefaultLevels(err error) slog.Level {
+	if err == nil {
+		return slog.LevelInfo
+	}
+	switch connect.CodeOf(err) {
+	case connect.CodeCanceled,
+		connect.CodeInvalidArgument,
+		connect.CodeNotFound,
+		connect.CodeAlreadyExists,
+		connect.CodeUnauthenticated:
+		return slog.LevelInfo
+	case connect.CodeDeadlineExceeded,
+		connect.CodePermissionDenied,
+		connect.CodeResourceExhausted,
+		connect.CodeFailedPrecondition,
+		connect.CodeAborted,
+		connect.CodeOutOfRange,
+		connect.CodeUnavailable:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+// WithRequestHeaders logs the values of the given request headers.
+func WithRequestHeaders(names ...string) Option {
+	return func(i *interceptor) {
+		i.requestHeaders = canonicalHeaders(names)
+	}
+}
+
+// WithResponseHeaders logs the values of the given response headers.
+func WithResponseHeaders(names ...string) Option {
+	return func(i *interceptor) {
+		i.responseHeaders = canonicalHeaders(names)
+	}
+}
+
+func canonicalHeaders(names []string) []string {
+	canonical := make([]string, len(names))
+	for i, name := range names {
+		canonical[i] = http.CanonicalHeaderKey(name)
+	}
+	return canonical
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"github.com/bufbuild/connect-go"
+	"io"
+	"sync"
+)
+
+type loggingHandler struct {
+	connect.StreamingHandlerConn
+	call *call
+}
+
+func (l *loggingHandler) Receive(msg any) error {
+	err := l.StreamingHandlerConn.Receive(msg)
+	if err == nil {
+		l.call.received.Add(1)
+	}
+	return err
+}
+
+func (l *loggingHandler) Send(msg any) error {
+	err := l.StreamingHandlerConn.Send(msg)
+	if err == nil {
+		l.call.sent.Add(1)
+	}
+	return err
+}
+
+// loggingClient logs the call once the response stream ends or is closed.
+type loggingClient struct {
+	connect.StreamingClientConn
+	interceptor *interceptor
+	ctx         context.Context
+	call        *call
+	logOnce     sync.Once
+}
+
+func (l *loggingClient) Send(msg any) error {
+	err := l.StreamingClientConn.Send(msg)
+	if err == nil {
+		l.call.sent.Add(1)
+	}
+	return err
+}
+
+func (l *loggingClient) Receive(msg any) error {
+	err := l.StreamingClientConn.Receive(msg)
+	switch {
+	case err == nil:
+		l.call.received.Add(1)
+	case errors.Is(err, io.EOF):
+		l.done(nil)
+	default:
+		l.done(err)
+	}
+	return err
+}
+
+func (l *loggingClient) CloseResponse() error {
+	err := l.StreamingClientConn.CloseResponse()
+	l.done(nil)
+	return err
+}
+
+func (l *loggingClient) done(err error) {
+	l.logOnce.Do(func() {
+		l.interceptor.log(l.ctx, l.call, l.RequestHeader(), l.ResponseHeader(), err)
+	})
+}
@@ -0,0 +1,91 @@
+package mtls
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/bufbuild/connect-go"
+	"github.com/hadrienk/connect-go-interceptors/common"
+	"net/http"
+)
+
+// An Identity is the identity of the verified client certificate of a call.
+type Identity struct {
+	// SPIFFEID is the first spiffe:// URI SAN of the certificate, if any.
+	SPIFFEID string
+	// DNSNames are the DNS SANs of the certificate.
+	DNSNames []string
+	// CommonName is the CN of the subject of the certificate.
+	CommonName  string
+	Certificate *x509.Certificate
+}
+
+// Name returns the SPIFFE ID of the identity, or its first DNS name, or its
+// common name.
+func (i *Identity) Name() string {
+	switch {
+	case i.SPIFFEID != "":
+		return i.SPIFFEID
+	case len(i.DNSNames) > 0:
+		return i.DNSNames[0]
+	default:
+		return i.CommonName
+	}
+}
+
+func newIdentity(certificate *x509.Certificate) *Identity {
+	identity := &Identity{
+		DNSNames:    certificate.DNSNames,
+		CommonName:  certificate.Subject.CommonName,
+		Certificate: certificate,
+	}
+	for _, uri := range certificate.URIs {
+		if uri.Scheme == "spiffe" {
+			identity.SPIFFEID = uri.String()
+			break
+		}
+	}
+	return identity
+}
+
+type identityKey struct{}
+
+// IdentityFromContext returns the identity of the call authenticated by the
+// interceptor returned by NewInterceptor.
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok
+}
+
+// NewHandler returns a handler that makes the TLS state of the connections
+// available to the interceptor. The connect handlers must be wrapped with it
+// and the server must verify the client certificates, e.g. with
+// tls.RequireAndVerifyClientCert.
+func NewHandler(handler http.Handler) http.Handler {
+	return common.NewTLSStateHandler(handler)
+}
+
+// NewInterceptor returns a server interceptor that authenticates the callers
+// from their verified client certificate and authorizes them with the policy.
+// The identity is added to the context, along with a common.Principal named
+// after Identity.Name.
+func NewInterceptor(policy Policy) connect.Interceptor {
+	patterns := policy.patterns()
+	return common.ContextSpecInterceptor(func(ctx context.Context, spec connect.Spec) (context.Context, error) {
+		state, ok := common.TLSStateFromContext(ctx)
+		if !ok || len(state.VerifiedChains) == 0 || len(state.PeerCertificates) == 0 {
+			return ctx, connect.NewError(connect.CodeUnauthenticated, errors.New("missing verified client certificate"))
+		}
+		identity := newIdentity(state.PeerCertificates[0])
+		if !policy.allows(patterns, spec.Procedure, identity) {
+			return ctx, connect.NewError(connect.CodePermissionDenied, fmt.Errorf("%s is not allowed to call %s", identity.Name(), spec.Procedure))
+		}
+		ctx = context.WithValue(ctx, identityKey{}, identity)
+		return common.ContextWithPrincipal(ctx, &common.Principal{
+			Subject:    identity.Name(),
+			Issuer:     state.VerifiedChains[0][len(state.VerifiedChains[0])-1].Subject.String(),
+			AuthMethod: common.AuthMethodMTLS,
+		}), nil
+	})
+}
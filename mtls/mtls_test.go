@@ -0,0 +1,172 @@
+package mtls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/bufbuild/connect-go"
+	"github.com/hadrienk/connect-go-interceptors/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	pool        *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate)
+	return &testCA{certificate: certificate, key: key, pool: pool}
+}
+
+// issue returns a certificate signed by the CA with the SANs of the template.
+func (ca *testCA) issue(t *testing.T, template *x509.Certificate, usage x509.ExtKeyUsage) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, key.Public(), ca.key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// newTestServer starts a server requiring client certificates of the CA that
+// returns the name of the identity of the calls in a header.
+func newTestServer(t *testing.T, ca *testCA, policy Policy) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/acme.foo.v1.FooService/", connect.NewUnaryHandler("/acme.foo.v1.FooService/Bar",
+		func(ctx context.Context, request *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+			identity, ok := IdentityFromContext(ctx)
+			assert.True(t, ok)
+			principal, ok := common.PrincipalFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, identity.Name(), principal.Subject)
+			assert.Equal(t, common.AuthMethodMTLS, principal.AuthMethod)
+			response := connect.NewResponse(&emptypb.Empty{})
+			response.Header().Set("Identity", identity.Name())
+			return response, nil
+		},
+		connect.WithInterceptors(NewInterceptor(policy)),
+	))
+	server := httptest.NewUnstartedServer(NewHandler(mux))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{ca.issue(t, &x509.Certificate{
+			IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		}, x509.ExtKeyUsageServerAuth)},
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  ca.pool,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func call(t *testing.T, server *httptest.Server, ca *testCA, certificates ...tls.Certificate) (string, error) {
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      ca.pool,
+		Certificates: certificates,
+	}}}
+	response, err := connect.NewClient[emptypb.Empty, emptypb.Empty](client, server.URL+"/acme.foo.v1.FooService/Bar").
+		CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	if err != nil {
+		return "", err
+	}
+	return response.Header().Get("Identity"), nil
+}
+
+func TestNewInterceptor(t *testing.T) {
+	ca := newTestCA(t)
+	server := newTestServer(t, ca, Policy{
+		"/acme.foo.v1.FooService/*": {
+			SPIFFEID("spiffe://example.org/ns/prod/*"),
+			DNSName("*.internal.example.org"),
+			CommonName("legacy-client"),
+		},
+	})
+	spiffeID, _ := url.Parse("spiffe://example.org/ns/prod/sa/billing")
+	otherSPIFFEID, _ := url.Parse("spiffe://example.org/ns/dev/sa/billing")
+
+	tests := []struct {
+		name     string
+		template *x509.Certificate
+		want     string
+		wantCode connect.Code
+	}{
+		{"allows SPIFFE IDs", &x509.Certificate{URIs: []*url.URL{spiffeID}, DNSNames: []string{"billing.internal.example.org"}}, spiffeID.String(), 0},
+		{"allows DNS names", &x509.Certificate{DNSNames: []string{"billing.internal.example.org"}}, "billing.internal.example.org", 0},
+		{"allows common names", &x509.Certificate{Subject: pkix.Name{CommonName: "legacy-client"}}, "legacy-client", 0},
+		{"denies other SPIFFE IDs", &x509.Certificate{URIs: []*url.URL{otherSPIFFEID}}, "", connect.CodePermissionDenied},
+		{"denies other DNS names", &x509.Certificate{DNSNames: []string{"a.b.internal.example.org"}}, "", connect.CodePermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, err := call(t, server, ca, ca.issue(t, tt.template, x509.ExtKeyUsageClientAuth))
+			if tt.wantCode == 0 {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, name)
+			} else {
+				assert.Equal(t, tt.wantCode, connect.CodeOf(err))
+			}
+		})
+	}
+
+	t.Run("requires a client certificate", func(t *testing.T) {
+		_, err := call(t, server, ca)
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+
+	t.Run("denies procedures without policy", func(t *testing.T) {
+		server := newTestServer(t, ca, Policy{
+			"/acme.admin.v1.AdminService/*": {CommonName("legacy-client")},
+		})
+		_, err := call(t, server, ca, ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "legacy-client"}}, x509.ExtKeyUsageClientAuth))
+		assert.Equal(t, connect.CodePermissionDenied, connect.CodeOf(err))
+	})
+}
+
+func TestPolicy_allows(t *testing.T) {
+	policy := Policy{
+		"/*":                          {CommonName("admin")},
+		"/acme.foo.v1.FooService/*":   {CommonName("foo")},
+		"/acme.foo.v1.FooService/Bar": {CommonName("bar")},
+	}
+	patterns := policy.patterns()
+	assert.True(t, policy.allows(patterns, "/acme.foo.v1.FooService/Bar", &Identity{CommonName: "bar"}))
+	assert.False(t, policy.allows(patterns, "/acme.foo.v1.FooService/Bar", &Identity{CommonName: "foo"}))
+	assert.True(t, policy.allows(patterns, "/acme.foo.v1.FooService/Baz", &Identity{CommonName: "foo"}))
+	assert.True(t, policy.allows(patterns, "/acme.other.v1.OtherService/Baz", &Identity{CommonName: "admin"}))
+	assert.False(t, policy.allows(patterns, "/acme.other.v1.OtherService/Baz", &Identity{CommonName: "foo"}))
+}
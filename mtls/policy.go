@@ -0,0 +1,92 @@
+package mtls
+
+import (
+	"github.com/hadrienk/connect-go-interceptors/common"
+	"strings"
+)
+
+// A Matcher returns true if the identity is allowed.
+type Matcher func(identity *Identity) bool
+
+// SPIFFEID allows the identities with one of the SPIFFE IDs. An ID ending with
+// /* allows all the IDs with the prefix, e.g. spiffe://example.org/* allows the
+// whole trust domain.
+func SPIFFEID(ids ...string) Matcher {
+	return func(identity *Identity) bool {
+		if identity.SPIFFEID == "" {
+			return false
+		}
+		for _, id := range ids {
+			if common.MatchesPattern(id, identity.SPIFFEID) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// DNSName allows the identities with one of the DNS names. A name starting with
+// *. allows one label, e.g. *.example.com allows foo.example.com.
+func DNSName(names ...string) Matcher {
+	return func(identity *Identity) bool {
+		for _, dnsName := range identity.DNSNames {
+			for _, name := range names {
+				if matchesDNSName(name, dnsName) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// CommonName allows the identities with one of the common names.
+func CommonName(names ...string) Matcher {
+	return func(identity *Identity) bool {
+		for _, name := range names {
+			if identity.CommonName != "" && identity.CommonName == name {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// A Policy maps procedures to the identities allowed to call them: an identity
+// is allowed if any of the matchers returns true. The keys are procedures like
+// /acme.foo.v1.FooService/Bar or prefixes ending with /* like
+// /acme.admin.v1.AdminService/*, and /* for all the procedures. Only the most
+// specific key matching a procedure applies: the procedure itself, then the
+// longest prefix. The calls of the procedures without key are denied.
+type Policy map[string][]Matcher
+
+// patterns returns the keys of the policy, the most specific first.
+func (p Policy) patterns() common.ProcedurePatterns {
+	keys := make([]string, 0, len(p))
+	for key := range p {
+		keys = append(keys, key)
+	}
+	return common.NewProcedurePatterns(keys...)
+}
+
+func (p Policy) allows(patterns common.ProcedurePatterns, procedure string, identity *Identity) bool {
+	pattern, ok := patterns.Match(procedure)
+	if !ok {
+		return false
+	}
+	for _, matcher := range p[pattern] {
+		if matcher(identity) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesDNSName(pattern, name string) bool {
+	pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	if strings.HasPrefix(pattern, "*.") {
+		label, domain, ok := strings.Cut(name, ".")
+		return ok && label != "" && domain == pattern[2:]
+	}
+	return pattern == name
+}
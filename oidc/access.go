@@ -0,0 +1,87 @@
+package oidcverify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bufbuild/connect-go"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v3"
+	"strings"
+)
+
+// An AccessToken is an OAuth2 access token in the JWT profile of RFC 9068.
+// The embedded oidc.IDToken holds the standard claims; its Audience is the
+// resource server.
+type AccessToken struct {
+	*oidc.IDToken
+	// ClientID is the client_id claim, the client the token was issued to.
+	ClientID string
+	// Scopes are the scopes of the space-delimited scope claim or of the scp
+	// array claim.
+	Scopes []string
+	// JWTID is the jti claim, if any.
+	JWTID string
+}
+
+type accessTokenKey struct{}
+
+// GetAccessToken returns the access token of the call verified by the
+// interceptor returned by NewAccessTokenInterceptor.
+func GetAccessToken(ctx context.Context) (*AccessToken, bool) {
+	accessToken, ok := ctx.Value(accessTokenKey{}).(*AccessToken)
+	return accessToken, ok
+}
+
+// NewAccessTokenInterceptor is like NewOIDCInterceptor but verifies OAuth2
+// access tokens as specified by RFC 9068 instead of ID tokens: the typ header
+// must be at+jwt and the token must have the sub and client_id claims. The
+// ClientID of the verifier config is the audience of the resource server.
+//
+// The handlers of WithHandler get the access token as an oidc.IDToken; use
+// GetAccessToken for the typed claims.
+func NewAccessTokenInterceptor(verifier *oidc.IDTokenVerifier, options ...option) connect.Interceptor {
+	return newInterceptor(func(ctx context.Context, rawToken string) (*verifiedToken, error) {
+		return verify(ctx, rawToken, Issuer{Verifier: verifier, AccessTokens: true})
+	}, options)
+}
+
+// verifyAccessToken verifies the access token specific parts of the raw
+// token, already verified by the oidc.IDTokenVerifier.
+func verifyAccessToken(rawToken string, token *verifiedToken) (*AccessToken, error) {
+	signature, err := jose.ParseSigned(rawToken)
+	if err != nil {
+		return nil, fmt.Errorf("malformed token: %w", err)
+	}
+	typ, _ := signature.Signatures[0].Header.ExtraHeaders[jose.HeaderType].(string)
+	if typ = strings.ToLower(typ); typ != "at+jwt" && typ != "application/at+jwt" {
+		return nil, fmt.Errorf("unexpected token type %q", typ)
+	}
+	claims, err := token.claims()
+	if err != nil {
+		return nil, err
+	}
+	if token.idToken.Subject == "" {
+		return nil, errors.New("missing sub claim")
+	}
+	clientID, _ := claims["client_id"].(string)
+	if clientID == "" {
+		return nil, errors.New("missing client_id claim")
+	}
+	jwtID, _ := claims["jti"].(string)
+	return &AccessToken{
+		IDToken:  token.idToken,
+		ClientID: clientID,
+		Scopes:   scopes(claims),
+		JWTID:    jwtID,
+	}, nil
+}
+
+// scopes returns the scopes of the space-delimited scope claim, or of the scp
+// claim that is an array or a space-delimited string.
+func scopes(claims map[string]any) []string {
+	if scope, ok := claims["scope"].(string); ok && scope != "" {
+		return strings.Fields(scope)
+	}
+	return stringValues(claims["scp"], strings.Fields)
+}
@@ -0,0 +1,59 @@
+package oidcverify
+
+import (
+	"github.com/bufbuild/connect-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewAccessTokenInterceptor(t *testing.T) {
+	issuer := newTestIssuer(t, "https://idp.example.com")
+	interceptor := NewAccessTokenInterceptor(issuer.verifier("https://api.example.com"))
+
+	t.Run("verifies access tokens", func(t *testing.T) {
+		token := issuer.accessToken(t, "https://api.example.com", map[string]any{
+			"client_id": "client",
+			"scope":     "read write",
+			"jti":       "id",
+		})
+		ctx, err := call(interceptor, token)
+		require.NoError(t, err)
+		accessToken, ok := GetAccessToken(ctx)
+		require.True(t, ok)
+		assert.Equal(t, "subject", accessToken.Subject)
+		assert.Equal(t, "client", accessToken.ClientID)
+		assert.Equal(t, []string{"read", "write"}, accessToken.Scopes)
+		assert.Equal(t, "id", accessToken.JWTID)
+		_, ok = GetToken(ctx)
+		assert.False(t, ok)
+	})
+
+	t.Run("reads scp claims", func(t *testing.T) {
+		token := issuer.accessToken(t, "https://api.example.com", map[string]any{
+			"client_id": "client",
+			"scp":       []string{"read", "write"},
+		})
+		ctx, err := call(interceptor, token)
+		require.NoError(t, err)
+		accessToken, _ := GetAccessToken(ctx)
+		assert.Equal(t, []string{"read", "write"}, accessToken.Scopes)
+	})
+
+	t.Run("rejects ID tokens", func(t *testing.T) {
+		_, err := call(interceptor, issuer.token(t, "https://api.example.com", map[string]any{"client_id": "client"}))
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+		assert.ErrorContains(t, err, "unexpected token type")
+	})
+
+	t.Run("rejects tokens of other resource servers", func(t *testing.T) {
+		_, err := call(interceptor, issuer.accessToken(t, "https://other.example.com", map[string]any{"client_id": "client"}))
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+
+	t.Run("requires client_id", func(t *testing.T) {
+		_, err := call(interceptor, issuer.accessToken(t, "https://api.example.com", nil))
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+		assert.ErrorContains(t, err, "missing client_id claim")
+	})
+}
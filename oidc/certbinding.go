@@ -0,0 +1,31 @@
+package oidcverify
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"github.com/bufbuild/connect-go"
+	"github.com/hadrienk/connect-go-interceptors/common"
+)
+
+// WithCertificateBinding requires the tokens to be bound to the client
+// certificate of the TLS connection as specified by RFC 8705: the cnf.x5t#S256
+// claim must be the SHA-256 thumbprint of the certificate. The handler must be
+// wrapped with common.NewTLSStateHandler for the interceptor to reach the
+// certificate.
+func WithCertificateBinding() option {
+	return option{authorize: func(token *verifiedToken) error {
+		state, ok := common.TLSStateFromContext(token.ctx)
+		if !ok || len(state.PeerCertificates) == 0 {
+			return connect.NewError(connect.CodeUnauthenticated, errors.New("missing client certificate"))
+		}
+		thumbprint := sha256.Sum256(state.PeerCertificates[0].Raw)
+		claims, _ := token.claims()
+		cnf, _ := claims["cnf"].(map[string]any)
+		x5t, _ := cnf["x5t#S256"].(string)
+		if x5t != base64.RawURLEncoding.EncodeToString(thumbprint[:]) {
+			return connect.NewError(connect.CodeUnauthenticated, errors.New("token not bound to the client certificate"))
+		}
+		return nil
+	}}
+}
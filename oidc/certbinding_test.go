@@ -0,0 +1,64 @@
+package oidcverify
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"github.com/bufbuild/connect-go"
+	"github.com/hadrienk/connect-go-interceptors/common"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// tlsContext returns the context of a request with the client certificate,
+// or without TLS if nil.
+func tlsContext(certificate *x509.Certificate) context.Context {
+	request := httptest.NewRequest(http.MethodPost, "/acme.foo.v1.FooService/Bar", nil)
+	if certificate != nil {
+		request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{certificate}}
+	}
+	var ctx context.Context
+	common.NewTLSStateHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})).ServeHTTP(httptest.NewRecorder(), request)
+	return ctx
+}
+
+func callContext(ctx context.Context, interceptor connect.Interceptor, token string) error {
+	request := serverRequest{Request: connect.NewRequest(&msg{}), procedure: "/acme.foo.v1.FooService/Bar"}
+	request.Header().Set("Authorization", "Bearer "+token)
+	_, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		return connect.NewResponse(&msg{}), nil
+	})(ctx, request)
+	return err
+}
+
+func TestWithCertificateBinding(t *testing.T) {
+	issuer := newTestIssuer(t, "https://idp.example.com")
+	certificate := &x509.Certificate{Raw: []byte("certificate")}
+	otherCertificate := &x509.Certificate{Raw: []byte("other")}
+	thumbprint := sha256.Sum256(certificate.Raw)
+	token := issuer.accessToken(t, "api", map[string]any{
+		"client_id": "client",
+		"cnf":       map[string]any{"x5t#S256": base64.RawURLEncoding.EncodeToString(thumbprint[:])},
+	})
+	unboundToken := issuer.accessToken(t, "api", map[string]any{"client_id": "client"})
+	interceptor := NewAccessTokenInterceptor(issuer.verifier("api"), WithCertificateBinding())
+
+	assert.NoError(t, callContext(tlsContext(certificate), interceptor, token))
+
+	err := callContext(tlsContext(otherCertificate), interceptor, token)
+	assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	assert.ErrorContains(t, err, "token not bound to the client certificate")
+
+	err = callContext(tlsContext(certificate), interceptor, unboundToken)
+	assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+
+	err = callContext(tlsContext(nil), interceptor, token)
+	assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	assert.ErrorContains(t, err, "missing client certificate")
+}
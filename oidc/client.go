@@ -0,0 +1,171 @@
+package oidcverify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/bufbuild/connect-go"
+	"golang.org/x/oauth2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultEarlyExpiry is how long before their expiry the tokens are refreshed.
+const defaultEarlyExpiry = 30 * time.Second
+
+var nowFunc = time.Now
+
+type clientOption func(*clientInterceptor)
+
+// WithEarlyExpiry refreshes the tokens the given duration before they expire
+// instead of 30 seconds before.
+func WithEarlyExpiry(earlyExpiry time.Duration) clientOption {
+	return func(c *clientInterceptor) {
+		c.source.earlyExpiry = earlyExpiry
+	}
+}
+
+// WithPassThrough forwards the token of the incoming call verified by the
+// interceptor returned by NewOIDCInterceptor when the context has one. The
+// token source is only used for the other calls and can be nil.
+func WithPassThrough() clientOption {
+	return func(c *clientInterceptor) {
+		c.passThrough = true
+	}
+}
+
+// NewOIDCClientInterceptor returns a client interceptor that sets the
+// Authorization header of the outbound calls to a bearer token from the
+// source, e.g. a clientcredentials.Config or an oauth2.Config with a refresh
+// token. The token is cached and refreshed before it expires.
+func NewOIDCClientInterceptor(source oauth2.TokenSource, options ...clientOption) connect.Interceptor {
+	c := &clientInterceptor{
+		source: &cachingTokenSource{
+			source:      source,
+			earlyExpiry: defaultEarlyExpiry,
+		},
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+type clientInterceptor struct {
+	source      *cachingTokenSource
+	passThrough bool
+}
+
+func (c *clientInterceptor) authorization(ctx context.Context) (string, error) {
+	if c.passThrough {
+		if rawToken, ok := GetRawToken(ctx); ok {
+			return "Bearer " + rawToken, nil
+		}
+	}
+	token, err := c.source.Token()
+	if err != nil {
+		return "", connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("could not get token: %w", err))
+	}
+	return token.Type() + " " + token.AccessToken, nil
+}
+
+func (c *clientInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		if !request.Spec().IsClient {
+			return next(ctx, request)
+		}
+		authorization, err := c.authorization(ctx)
+		if err != nil {
+			return nil, err
+		}
+		request.Header().Set("Authorization", authorization)
+		return next(ctx, request)
+	}
+}
+
+func (c *clientInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		authorization, err := c.authorization(ctx)
+		if err != nil {
+			return &failedClientConn{StreamingClientConn: conn, err: err}
+		}
+		conn.RequestHeader().Set("Authorization", authorization)
+		return conn
+	}
+}
+
+func (c *clientInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+// failedClientConn fails the stream with the error since the
+// StreamingClientFunc cannot return it. It never reaches the wrapped
+// connection, which would send the request without authorization.
+type failedClientConn struct {
+	connect.StreamingClientConn
+	err error
+}
+
+func (f *failedClientConn) Send(any) error {
+	return f.err
+}
+
+func (f *failedClientConn) CloseRequest() error {
+	return f.err
+}
+
+func (f *failedClientConn) Receive(any) error {
+	return f.err
+}
+
+// ResponseHeader is empty since the request is never sent. The wrapped
+// connection would block until the response is received.
+func (f *failedClientConn) ResponseHeader() http.Header {
+	return http.Header{}
+}
+
+func (f *failedClientConn) ResponseTrailer() http.Header {
+	return http.Header{}
+}
+
+func (f *failedClientConn) CloseResponse() error {
+	return nil
+}
+
+// cachingTokenSource is like oauth2.ReuseTokenSource with a configurable
+// expiry delta.
+type cachingTokenSource struct {
+	mu          sync.Mutex
+	source      oauth2.TokenSource
+	token       *oauth2.Token
+	earlyExpiry time.Duration
+}
+
+func (c *cachingTokenSource) Token() (*oauth2.Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.valid() {
+		return c.token, nil
+	}
+	if c.source == nil {
+		return nil, errors.New("no token source")
+	}
+	token, err := c.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	c.token = token
+	return token, nil
+}
+
+func (c *cachingTokenSource) valid() bool {
+	if c.token == nil || c.token.AccessToken == "" {
+		return false
+	}
+	if c.token.Expiry.IsZero() {
+		return true
+	}
+	return nowFunc().Add(c.earlyExpiry).Before(c.token.Expiry)
+}
@@ -0,0 +1,185 @@
+package oidcverify
+
+import (
+	"context"
+	"errors"
+	"github.com/bufbuild/connect-go"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type msg struct {
+}
+
+type countingTokenSource struct {
+	calls  int
+	expiry time.Time
+	err    error
+}
+
+func (c *countingTokenSource) Token() (*oauth2.Token, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &oauth2.Token{AccessToken: "token", Expiry: c.expiry}, nil
+}
+
+type clientRequest struct {
+	*connect.Request[msg]
+}
+
+func (c clientRequest) Spec() connect.Spec {
+	return connect.Spec{IsClient: true}
+}
+
+func callUnary(t *testing.T, interceptor connect.Interceptor, ctx context.Context) (http.Header, error) {
+	var header http.Header
+	_, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		header = request.Header()
+		return connect.NewResponse(&msg{}), nil
+	})(ctx, clientRequest{connect.NewRequest(&msg{})})
+	return header, err
+}
+
+func TestNewOIDCClientInterceptor(t *testing.T) {
+	defer func() {
+		nowFunc = time.Now
+	}()
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return now
+	}
+
+	t.Run("sets the bearer token and caches it", func(t *testing.T) {
+		source := &countingTokenSource{expiry: now.Add(time.Hour)}
+		interceptor := NewOIDCClientInterceptor(source)
+		for i := 0; i < 2; i++ {
+			header, err := callUnary(t, interceptor, context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "Bearer token", header.Get("Authorization"))
+		}
+		assert.Equal(t, 1, source.calls)
+	})
+
+	t.Run("refreshes the token before expiry", func(t *testing.T) {
+		source := &countingTokenSource{expiry: now.Add(time.Minute)}
+		interceptor := NewOIDCClientInterceptor(source, WithEarlyExpiry(2*time.Minute))
+		for i := 0; i < 2; i++ {
+			_, err := callUnary(t, interceptor, context.Background())
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, 2, source.calls)
+	})
+
+	t.Run("fails without token", func(t *testing.T) {
+		interceptor := NewOIDCClientInterceptor(&countingTokenSource{err: errors.New("denied")})
+		_, err := callUnary(t, interceptor, context.Background())
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+
+	t.Run("passes the incoming token through", func(t *testing.T) {
+		interceptor := NewOIDCClientInterceptor(nil, WithPassThrough())
+		ctx := context.WithValue(context.Background(), rawTokenKey{}, "incoming")
+		header, err := callUnary(t, interceptor, ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer incoming", header.Get("Authorization"))
+
+		_, err = callUnary(t, interceptor, context.Background())
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+
+	t.Run("ignores handlers", func(t *testing.T) {
+		source := &countingTokenSource{}
+		_, err := NewOIDCClientInterceptor(source).WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			return connect.NewResponse(&msg{}), nil
+		})(context.Background(), connect.NewRequest(&msg{}))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, source.calls)
+	})
+
+	t.Run("sets the streaming request header", func(t *testing.T) {
+		interceptor := NewOIDCClientInterceptor(&countingTokenSource{})
+		header := http.Header{}
+		conn := interceptor.WrapStreamingClient(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+			return noOpClientConn{header: header}
+		})(context.Background(), connect.Spec{IsClient: true})
+		assert.NoError(t, conn.Send(&msg{}))
+		assert.Equal(t, "Bearer token", header.Get("Authorization"))
+	})
+
+	t.Run("fails the stream without token", func(t *testing.T) {
+		interceptor := NewOIDCClientInterceptor(&countingTokenSource{err: errors.New("denied")})
+		wrapped := &closingClientConn{noOpClientConn: noOpClientConn{header: http.Header{}}}
+		conn := interceptor.WrapStreamingClient(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+			return wrapped
+		})(context.Background(), connect.Spec{IsClient: true})
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(conn.Send(&msg{})))
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(conn.CloseRequest()))
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(conn.Receive(&msg{})))
+		assert.Empty(t, conn.ResponseHeader())
+		assert.NoError(t, conn.CloseResponse())
+		assert.False(t, wrapped.closedRequest, "closed the wrapped request")
+		assert.False(t, wrapped.closedResponse, "closed the wrapped response")
+	})
+}
+
+// closingClientConn records the calls to CloseRequest and CloseResponse.
+type closingClientConn struct {
+	noOpClientConn
+	closedRequest  bool
+	closedResponse bool
+}
+
+func (c *closingClientConn) CloseRequest() error {
+	c.closedRequest = true
+	return nil
+}
+
+func (c *closingClientConn) CloseResponse() error {
+	c.closedResponse = true
+	return nil
+}
+
+type noOpClientConn struct {
+	header http.Header
+}
+
+func (n noOpClientConn) Spec() connect.Spec {
+	return connect.Spec{IsClient: true}
+}
+
+func (n noOpClientConn) Peer() connect.Peer {
+	return connect.Peer{}
+}
+
+func (n noOpClientConn) Send(msg any) error {
+	return nil
+}
+
+func (n noOpClientConn) RequestHeader() http.Header {
+	return n.header
+}
+
+func (n noOpClientConn) CloseRequest() error {
+	return nil
+}
+
+func (n noOpClientConn) Receive(msg any) error {
+	return nil
+}
+
+func (n noOpClientConn) ResponseHeader() http.Header {
+	return http.Header{}
+}
+
+func (n noOpClientConn) ResponseTrailer() http.Header {
+	return http.Header{}
+}
+
+func (n noOpClientConn) CloseResponse() error {
+	return nil
+}
@@ -0,0 +1,186 @@
+package oidcverify
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bufbuild/connect-go"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/hadrienk/connect-go-interceptors/common"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// A ReplayStore remembers the jti of the DPoP proofs to reject replayed proofs.
+type ReplayStore interface {
+	// CheckAndStore stores the jti until the expiry and returns true, or returns
+	// false if the jti is already stored.
+	CheckAndStore(ctx context.Context, jti string, expiry time.Time) (bool, error)
+}
+
+// DPoPConfig configures WithDPoP.
+type DPoPConfig struct {
+	// BaseURL is the URL of the server, e.g. https://api.example.com. The htu
+	// claim of the proofs must be the BaseURL followed by the procedure. If
+	// empty, only the path of the htu claim is checked.
+	BaseURL string
+	// Method is the expected htm claim. Defaults to POST.
+	Method string
+	// MaxAge is how old, or how far in the future, the iat claim of the proofs
+	// can be. Defaults to five minutes.
+	MaxAge time.Duration
+	// ReplayStore defaults to an in-memory store that only rejects the proofs
+	// replayed against the same process.
+	ReplayStore ReplayStore
+}
+
+// WithDPoP requires the tokens to be DPoP-bound as specified by RFC 9449: the
+// Authorization header must use the DPoP scheme and the DPoP header must hold
+// a proof signed with the key of the cnf.jkt claim of the token, for the
+// procedure of the call, and never seen before. Server provided nonces are not
+// supported. The DPoP scheme and the tokens with a cnf.jkt claim are rejected
+// on the calls that WithDPoP does not apply to.
+func WithDPoP(config DPoPConfig) option {
+	if config.Method == "" {
+		config.Method = http.MethodPost
+	}
+	if config.MaxAge == 0 {
+		config.MaxAge = 5 * time.Minute
+	}
+	if config.ReplayStore == nil {
+		config.ReplayStore = NewMemoryReplayStore()
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	return option{authorize: func(token *verifiedToken) error {
+		if err := verifyDPoP(config, token); err != nil {
+			return connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("invalid DPoP proof: %w", err))
+		}
+		token.dpopVerified = true
+		return nil
+	}}
+}
+
+// checkDPoP rejects the calls using the DPoP scheme or a DPoP-bound token
+// whose proof was not verified by WithDPoP, e.g. because the policy of the
+// procedure does not require it. A stolen bound token cannot be used without
+// its proof on the other procedures.
+func checkDPoP(token *verifiedToken) error {
+	if token.dpopVerified {
+		return nil
+	}
+	if token.scheme == "DPoP" {
+		return connect.NewError(connect.CodeUnauthenticated, errors.New("DPoP authorization scheme without DPoP verification"))
+	}
+	if confirmation(token) != "" {
+		return connect.NewError(connect.CodeUnauthenticated, errors.New("DPoP-bound token without proof"))
+	}
+	return nil
+}
+
+func verifyDPoP(config DPoPConfig, token *verifiedToken) error {
+	if token.scheme != "DPoP" {
+		return errors.New("expected DPoP authorization scheme")
+	}
+	proofs := token.header.Values("DPoP")
+	if len(proofs) != 1 {
+		return errors.New("expected one DPoP header")
+	}
+	signature, err := jose.ParseSigned(proofs[0])
+	if err != nil {
+		return fmt.Errorf("malformed proof: %w", err)
+	}
+	header := signature.Signatures[0].Header
+	if typ, _ := header.ExtraHeaders[jose.HeaderType].(string); typ != "dpop+jwt" {
+		return fmt.Errorf("unexpected type %q", typ)
+	}
+	if header.JSONWebKey == nil || !header.JSONWebKey.IsPublic() || strings.HasPrefix(header.Algorithm, "HS") {
+		return errors.New("expected an asymmetric public jwk")
+	}
+	payload, err := signature.Verify(header.JSONWebKey)
+	if err != nil {
+		return err
+	}
+	var claims struct {
+		JWTID    string `json:"jti"`
+		Method   string `json:"htm"`
+		URI      string `json:"htu"`
+		IssuedAt int64  `json:"iat"`
+		Hash     string `json:"ath"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return fmt.Errorf("malformed claims: %w", err)
+	}
+	if claims.Method != config.Method {
+		return fmt.Errorf("unexpected htm %q", claims.Method)
+	}
+	if !matchesURI(config.BaseURL, token.procedure, claims.URI) {
+		return fmt.Errorf("unexpected htu %q", claims.URI)
+	}
+	issuedAt := time.Unix(claims.IssuedAt, 0)
+	if now := nowFunc(); issuedAt.Before(now.Add(-config.MaxAge)) || issuedAt.After(now.Add(config.MaxAge)) {
+		return errors.New("expired proof")
+	}
+	hash := sha256.Sum256([]byte(token.rawToken))
+	if claims.Hash != base64.RawURLEncoding.EncodeToString(hash[:]) {
+		return errors.New("unexpected ath")
+	}
+	thumbprint, err := header.JSONWebKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return err
+	}
+	if confirmation(token) != base64.RawURLEncoding.EncodeToString(thumbprint) {
+		return errors.New("token not bound to the proof key")
+	}
+	if claims.JWTID == "" {
+		return errors.New("missing jti")
+	}
+	fresh, err := config.ReplayStore.CheckAndStore(token.ctx, claims.JWTID, issuedAt.Add(config.MaxAge))
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return errors.New("replayed proof")
+	}
+	return nil
+}
+
+// confirmation returns the cnf.jkt claim of the token.
+func confirmation(token *verifiedToken) string {
+	claims, _ := token.claims()
+	cnf, _ := claims["cnf"].(map[string]any)
+	jkt, _ := cnf["jkt"].(string)
+	return jkt
+}
+
+// matchesURI returns true if the htu claim is the URL of the procedure,
+// ignoring the query and fragment.
+func matchesURI(baseURL, procedure, htu string) bool {
+	uri, err := url.Parse(htu)
+	if err != nil {
+		return false
+	}
+	uri.RawQuery, uri.Fragment = "", ""
+	if baseURL == "" {
+		return strings.HasSuffix(uri.Path, procedure)
+	}
+	return uri.String() == baseURL+procedure
+}
+
+// NewMemoryReplayStore returns a ReplayStore keeping the jti in memory.
+func NewMemoryReplayStore() ReplayStore {
+	return memoryReplayStore{seen: common.NewExpiringSet()}
+}
+
+type memoryReplayStore struct {
+	seen *common.ExpiringSet
+}
+
+func (m memoryReplayStore) CheckAndStore(_ context.Context, jti string, expiry time.Time) (bool, error) {
+	return m.seen.Add(jti, nowFunc(), expiry), nil
+}
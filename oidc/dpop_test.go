@@ -0,0 +1,170 @@
+package oidcverify
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/bufbuild/connect-go"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type dpopKey struct {
+	signer     jose.Signer
+	thumbprint string
+}
+
+func newDPoPKey(t *testing.T) *dpopKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, (&jose.SignerOptions{EmbedJWK: true}).WithType("dpop+jwt"))
+	require.NoError(t, err)
+	thumbprint, err := (&jose.JSONWebKey{Key: key.Public()}).Thumbprint(crypto.SHA256)
+	require.NoError(t, err)
+	return &dpopKey{signer: signer, thumbprint: base64.RawURLEncoding.EncodeToString(thumbprint)}
+}
+
+// proof signs a proof for the token with the given claims on top of the
+// standard ones.
+func (k *dpopKey) proof(t *testing.T, token string, claims map[string]any) string {
+	hash := sha256.Sum256([]byte(token))
+	jti := make([]byte, 16)
+	_, err := rand.Read(jti)
+	require.NoError(t, err)
+	payload := map[string]any{
+		"jti": base64.RawURLEncoding.EncodeToString(jti),
+		"htm": "POST",
+		"htu": "https://api.example.com/acme.foo.v1.FooService/Bar",
+		"iat": time.Now().Unix(),
+		"ath": base64.RawURLEncoding.EncodeToString(hash[:]),
+	}
+	for name, value := range claims {
+		payload[name] = value
+	}
+	bytes, err := json.Marshal(payload)
+	require.NoError(t, err)
+	signature, err := k.signer.Sign(bytes)
+	require.NoError(t, err)
+	proof, err := signature.CompactSerialize()
+	require.NoError(t, err)
+	return proof
+}
+
+func callDPoP(interceptor connect.Interceptor, scheme, token, proof string) error {
+	request := serverRequest{Request: connect.NewRequest(&msg{}), procedure: "/acme.foo.v1.FooService/Bar"}
+	request.Header().Set("Authorization", scheme+" "+token)
+	if proof != "" {
+		request.Header().Set("DPoP", proof)
+	}
+	_, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		return connect.NewResponse(&msg{}), nil
+	})(context.Background(), request)
+	return err
+}
+
+func TestWithDPoP(t *testing.T) {
+	issuer := newTestIssuer(t, "https://idp.example.com")
+	key := newDPoPKey(t)
+	token := issuer.accessToken(t, "api", map[string]any{
+		"client_id": "client",
+		"cnf":       map[string]any{"jkt": key.thumbprint},
+	})
+	interceptor := NewAccessTokenInterceptor(issuer.verifier("api"), WithDPoP(DPoPConfig{BaseURL: "https://api.example.com/"}))
+
+	t.Run("accepts valid proofs", func(t *testing.T) {
+		assert.NoError(t, callDPoP(interceptor, "DPoP", token, key.proof(t, token, nil)))
+	})
+
+	t.Run("rejects replayed proofs", func(t *testing.T) {
+		proof := key.proof(t, token, map[string]any{"jti": "replayed"})
+		assert.NoError(t, callDPoP(interceptor, "DPoP", token, proof))
+		err := callDPoP(interceptor, "DPoP", token, proof)
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+		assert.ErrorContains(t, err, "replayed proof")
+	})
+
+	otherKey := newDPoPKey(t)
+	tests := []struct {
+		name   string
+		scheme string
+		proof  string
+		want   string
+	}{
+		{"rejects the bearer scheme", "Bearer", key.proof(t, token, nil), "expected DPoP authorization scheme"},
+		{"rejects missing proofs", "DPoP", "", "expected one DPoP header"},
+		{"rejects other methods", "DPoP", key.proof(t, token, map[string]any{"htm": "GET"}), "unexpected htm"},
+		{"rejects other procedures", "DPoP", key.proof(t, token, map[string]any{"htu": "https://api.example.com/acme.foo.v1.FooService/Baz"}), "unexpected htu"},
+		{"rejects other hosts", "DPoP", key.proof(t, token, map[string]any{"htu": "https://evil.example.com/acme.foo.v1.FooService/Bar"}), "unexpected htu"},
+		{"rejects old proofs", "DPoP", key.proof(t, token, map[string]any{"iat": time.Now().Add(-time.Hour).Unix()}), "expired proof"},
+		{"rejects proofs of other tokens", "DPoP", key.proof(t, "other", nil), "unexpected ath"},
+		{"rejects proofs of other keys", "DPoP", otherKey.proof(t, token, nil), "token not bound to the proof key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := callDPoP(interceptor, tt.scheme, token, tt.proof)
+			assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func Test_matchesURI(t *testing.T) {
+	assert.True(t, matchesURI("", "/foo.v1.Foo/Bar", "https://api.example.com/prefix/foo.v1.Foo/Bar?query"))
+	assert.False(t, matchesURI("", "/foo.v1.Foo/Bar", "https://api.example.com/foo.v1.Foo/Baz"))
+	assert.True(t, matchesURI("https://api.example.com", "/foo.v1.Foo/Bar", "https://api.example.com/foo.v1.Foo/Bar#fragment"))
+	assert.False(t, matchesURI("https://api.example.com", "/foo.v1.Foo/Bar", "http://api.example.com/foo.v1.Foo/Bar"))
+}
+
+func Test_memoryReplayStore(t *testing.T) {
+	store := NewMemoryReplayStore()
+	expiry := time.Now().Add(time.Minute)
+	fresh, err := store.CheckAndStore(context.Background(), "jti", expiry)
+	assert.NoError(t, err)
+	assert.True(t, fresh)
+	fresh, err = store.CheckAndStore(context.Background(), "jti", expiry)
+	assert.NoError(t, err)
+	assert.False(t, fresh)
+	fresh, _ = store.CheckAndStore(context.Background(), "expired", time.Now().Add(-time.Minute))
+	assert.True(t, fresh)
+	fresh, _ = store.CheckAndStore(context.Background(), "expired", expiry)
+	assert.True(t, fresh)
+}
+
+func TestWithDPoP_notApplied(t *testing.T) {
+	issuer := newTestIssuer(t, "https://idp.example.com")
+	key := newDPoPKey(t)
+	boundToken := issuer.accessToken(t, "api", map[string]any{
+		"client_id": "client",
+		"cnf":       map[string]any{"jkt": key.thumbprint},
+	})
+	token := issuer.accessToken(t, "api", map[string]any{"client_id": "client"})
+	interceptor := NewAccessTokenInterceptor(issuer.verifier("api"), WithPolicy(Policy{
+		"/acme.foo.v1.FooService/Bar": {WithDPoP(DPoPConfig{BaseURL: "https://api.example.com"})},
+	}))
+
+	t.Run("accepts bound tokens with proofs where required", func(t *testing.T) {
+		assert.NoError(t, callDPoP(interceptor, "DPoP", boundToken, key.proof(t, boundToken, nil)))
+	})
+	t.Run("accepts bearer tokens elsewhere", func(t *testing.T) {
+		_, err := callProcedure(interceptor, "/acme.foo.v1.FooService/Baz", token)
+		assert.NoError(t, err)
+	})
+	t.Run("rejects bound tokens without proofs elsewhere", func(t *testing.T) {
+		_, err := callProcedure(interceptor, "/acme.foo.v1.FooService/Baz", boundToken)
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+		assert.ErrorContains(t, err, "DPoP-bound token without proof")
+	})
+	t.Run("rejects the DPoP scheme without verification", func(t *testing.T) {
+		err := callDPoP(NewAccessTokenInterceptor(issuer.verifier("api")), "DPoP", token, "")
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+		assert.ErrorContains(t, err, "DPoP authorization scheme without DPoP verification")
+	})
+}
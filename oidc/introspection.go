@@ -0,0 +1,255 @@
+package oidcverify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bufbuild/connect-go"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IntrospectionConfig configures the interceptor returned by
+// NewIntrospectionInterceptor.
+type IntrospectionConfig struct {
+	// Endpoint is the URL of the introspection endpoint.
+	Endpoint string
+	// ClientID and ClientSecret authenticate the resource server to the
+	// endpoint with HTTP basic authentication if ClientID is not empty.
+	ClientID     string
+	ClientSecret string
+	// Client calls the endpoint. Defaults to http.DefaultClient. Use an
+	// authenticated client, e.g. from clientcredentials.Config, for the other
+	// authentication methods.
+	Client *http.Client
+	// Audience must be one of the audiences of the tokens if not empty.
+	Audience string
+	// CacheTTL is how long the active tokens are cached, at most until they
+	// expire. Defaults to one minute.
+	CacheTTL time.Duration
+	// NegativeCacheTTL is how long the inactive tokens are cached. Defaults to
+	// ten seconds.
+	NegativeCacheTTL time.Duration
+	// MaxCacheEntries limits the size of the cache. Defaults to 10000.
+	MaxCacheEntries int
+}
+
+// An IntrospectedToken is an active token returned by the introspection
+// endpoint, see RFC 7662.
+type IntrospectedToken struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ClientID  string
+	Username  string
+	TokenType string
+	Scopes    []string
+	Expiry    time.Time
+	IssuedAt  time.Time
+	NotBefore time.Time
+	JWTID     string
+	// Claims are all the members of the introspection response.
+	Claims map[string]any
+}
+
+// copy returns a copy of the token so that the calls sharing a cached token
+// cannot modify it. The values of the claims are not copied.
+func (t *IntrospectedToken) copy() *IntrospectedToken {
+	token := *t
+	token.Audience = append([]string(nil), t.Audience...)
+	token.Scopes = append([]string(nil), t.Scopes...)
+	token.Claims = make(map[string]any, len(t.Claims))
+	for name, value := range t.Claims {
+		token.Claims[name] = value
+	}
+	return &token
+}
+
+type introspectedTokenKey struct{}
+
+// GetIntrospectedToken returns the token of the call verified by the
+// interceptor returned by NewIntrospectionInterceptor.
+func GetIntrospectedToken(ctx context.Context) (*IntrospectedToken, bool) {
+	token, ok := ctx.Value(introspectedTokenKey{}).(*IntrospectedToken)
+	return token, ok
+}
+
+// NewIntrospectionInterceptor is like NewOIDCInterceptor but verifies opaque
+// tokens with the introspection endpoint of the authorization server as
+// specified by RFC 7662. The results are cached, the inactive tokens included.
+func NewIntrospectionInterceptor(config IntrospectionConfig, options ...option) connect.Interceptor {
+	introspector := newIntrospector(config)
+	return newInterceptor(introspector.authenticate, options)
+}
+
+type introspector struct {
+	config  IntrospectionConfig
+	cacheMu sync.Mutex
+	cache   map[[sha256.Size]byte]introspectionResult
+}
+
+// introspectionResult is a cached result. The token is nil if inactive.
+type introspectionResult struct {
+	token   *IntrospectedToken
+	expires time.Time
+}
+
+func newIntrospector(config IntrospectionConfig) *introspector {
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = time.Minute
+	}
+	if config.NegativeCacheTTL == 0 {
+		config.NegativeCacheTTL = 10 * time.Second
+	}
+	if config.MaxCacheEntries == 0 {
+		config.MaxCacheEntries = 10000
+	}
+	return &introspector{
+		config: config,
+		cache:  make(map[[sha256.Size]byte]introspectionResult),
+	}
+}
+
+func (i *introspector) authenticate(ctx context.Context, rawToken string) (*verifiedToken, error) {
+	if rawToken == "" {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("missing token"))
+	}
+	key := sha256.Sum256([]byte(rawToken))
+	result, ok := i.cached(key)
+	if !ok {
+		token, err := i.introspect(ctx, rawToken)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeUnavailable, fmt.Errorf("could not introspect token: %w", err))
+		}
+		result = i.store(key, token)
+	}
+	if result.token == nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("inactive token"))
+	}
+	if i.config.Audience != "" && !contains(result.token.Audience, i.config.Audience) {
+		return nil, connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("expected audience %q got %q", i.config.Audience, result.token.Audience))
+	}
+	token := result.token.copy()
+	return &verifiedToken{
+		idToken: &oidc.IDToken{
+			Issuer:   token.Issuer,
+			Subject:  token.Subject,
+			Audience: token.Audience,
+			Expiry:   token.Expiry,
+			IssuedAt: token.IssuedAt,
+		},
+		introspectedToken: token,
+		decodedClaims:     token.Claims,
+	}, nil
+}
+
+func (i *introspector) cached(key [sha256.Size]byte) (introspectionResult, bool) {
+	i.cacheMu.Lock()
+	defer i.cacheMu.Unlock()
+	result, ok := i.cache[key]
+	if !ok {
+		return result, false
+	}
+	if !nowFunc().Before(result.expires) {
+		delete(i.cache, key)
+		return result, false
+	}
+	return result, true
+}
+
+// store caches the token for the CacheTTL, bounded by its expiry, or for the
+// NegativeCacheTTL if it is nil.
+func (i *introspector) store(key [sha256.Size]byte, token *IntrospectedToken) introspectionResult {
+	now := nowFunc()
+	result := introspectionResult{token: token, expires: now.Add(i.config.NegativeCacheTTL)}
+	if token != nil {
+		result.expires = now.Add(i.config.CacheTTL)
+		if !token.Expiry.IsZero() && token.Expiry.Before(result.expires) {
+			result.expires = token.Expiry
+		}
+	}
+	i.cacheMu.Lock()
+	defer i.cacheMu.Unlock()
+	if len(i.cache) >= i.config.MaxCacheEntries {
+		i.evict(now)
+	}
+	i.cache[key] = result
+	return result
+}
+
+// evict removes the expired results, or an arbitrary one if none expired.
+func (i *introspector) evict(now time.Time) {
+	for key, result := range i.cache {
+		if !now.Before(result.expires) {
+			delete(i.cache, key)
+		}
+	}
+	for key := range i.cache {
+		if len(i.cache) < i.config.MaxCacheEntries {
+			return
+		}
+		delete(i.cache, key)
+	}
+}
+
+// introspect calls the endpoint and returns the token, or nil if it is inactive.
+func (i *introspector) introspect(ctx context.Context, rawToken string) (*IntrospectedToken, error) {
+	form := url.Values{"token": {rawToken}, "token_type_hint": {"access_token"}}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, i.config.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if i.config.ClientID != "" {
+		request.SetBasicAuth(url.QueryEscape(i.config.ClientID), url.QueryEscape(i.config.ClientSecret))
+	}
+	response, err := i.config.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", response.Status)
+	}
+	var claims map[string]any
+	if err := json.NewDecoder(response.Body).Decode(&claims); err != nil {
+		return nil, fmt.Errorf("malformed response: %w", err)
+	}
+	if active, _ := claims["active"].(bool); !active {
+		return nil, nil
+	}
+	token := &IntrospectedToken{
+		Audience:  stringValues(claims["aud"], func(s string) []string { return []string{s} }),
+		Scopes:    scopes(claims),
+		Expiry:    timeClaim(claims["exp"]),
+		IssuedAt:  timeClaim(claims["iat"]),
+		NotBefore: timeClaim(claims["nbf"]),
+		Claims:    claims,
+	}
+	token.Issuer, _ = claims["iss"].(string)
+	token.Subject, _ = claims["sub"].(string)
+	token.ClientID, _ = claims["client_id"].(string)
+	token.Username, _ = claims["username"].(string)
+	token.TokenType, _ = claims["token_type"].(string)
+	token.JWTID, _ = claims["jti"].(string)
+	return token, nil
+}
+
+// timeClaim returns the time of a claim in seconds since the epoch.
+func timeClaim(value any) time.Time {
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(seconds), 0)
+}
@@ -0,0 +1,148 @@
+package oidcverify
+
+import (
+	"encoding/json"
+	"github.com/bufbuild/connect-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newIntrospectionServer(t *testing.T, expiry time.Time, calls *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		clientID, clientSecret, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "api", clientID)
+		assert.Equal(t, "secret", clientSecret)
+		assert.Equal(t, "access_token", r.PostFormValue("token_type_hint"))
+		response := map[string]any{"active": false}
+		switch r.PostFormValue("token") {
+		case "active":
+			response = map[string]any{
+				"active":    true,
+				"iss":       "https://idp.example.com",
+				"sub":       "subject",
+				"aud":       "https://api.example.com",
+				"client_id": "client",
+				"scope":     "read write",
+				"exp":       expiry.Unix(),
+				"roles":     []string{"admin"},
+			}
+		case "error":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(response))
+	}))
+}
+
+func TestNewIntrospectionInterceptor(t *testing.T) {
+	defer func() {
+		nowFunc = time.Now
+	}()
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time {
+		return now
+	}
+	calls := 0
+	server := newIntrospectionServer(t, now.Add(30*time.Second), &calls)
+	defer server.Close()
+	config := IntrospectionConfig{
+		Endpoint:     server.URL,
+		ClientID:     "api",
+		ClientSecret: "secret",
+		Audience:     "https://api.example.com",
+	}
+
+	t.Run("verifies active tokens", func(t *testing.T) {
+		calls = 0
+		interceptor := NewIntrospectionInterceptor(config, WithScope("read"), WithRole("admin"))
+		ctx, err := call(interceptor, "active")
+		require.NoError(t, err)
+		token, ok := GetIntrospectedToken(ctx)
+		require.True(t, ok)
+		assert.Equal(t, "subject", token.Subject)
+		assert.Equal(t, "client", token.ClientID)
+		assert.Equal(t, []string{"read", "write"}, token.Scopes)
+		assert.Equal(t, []string{"https://api.example.com"}, token.Audience)
+		assert.Equal(t, now.Add(30*time.Second).Unix(), token.Expiry.Unix())
+		rawToken, _ := GetRawToken(ctx)
+		assert.Equal(t, "active", rawToken)
+	})
+
+	t.Run("applies the options", func(t *testing.T) {
+		interceptor := NewIntrospectionInterceptor(config, WithScope("admin"))
+		_, err := call(interceptor, "active")
+		assert.Equal(t, connect.CodePermissionDenied, connect.CodeOf(err))
+	})
+
+	t.Run("checks the audience", func(t *testing.T) {
+		config := config
+		config.Audience = "https://other.example.com"
+		_, err := call(NewIntrospectionInterceptor(config), "active")
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+
+	t.Run("caches active tokens until they expire", func(t *testing.T) {
+		calls = 0
+		interceptor := NewIntrospectionInterceptor(config)
+		for i := 0; i < 2; i++ {
+			ctx, err := call(interceptor, "active")
+			require.NoError(t, err)
+			token, _ := GetIntrospectedToken(ctx)
+			assert.Equal(t, "subject", token.Claims["sub"], "modified the cached claims")
+			assert.Equal(t, "subject", token.Subject)
+			token.Claims["sub"] = "mallory"
+			token.Subject = "mallory"
+		}
+		assert.Equal(t, 1, calls)
+
+		now = now.Add(30 * time.Second)
+		defer func() {
+			now = now.Add(-30 * time.Second)
+		}()
+		_, err := call(interceptor, "active")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("caches inactive tokens", func(t *testing.T) {
+		calls = 0
+		interceptor := NewIntrospectionInterceptor(config)
+		for i := 0; i < 2; i++ {
+			_, err := call(interceptor, "inactive")
+			assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+		}
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("does not cache errors", func(t *testing.T) {
+		calls = 0
+		interceptor := NewIntrospectionInterceptor(config)
+		for i := 0; i < 2; i++ {
+			_, err := call(interceptor, "error")
+			assert.Equal(t, connect.CodeUnavailable, connect.CodeOf(err))
+		}
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("rejects missing tokens", func(t *testing.T) {
+		calls = 0
+		_, err := call(NewIntrospectionInterceptor(config), "")
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+		assert.Equal(t, 0, calls)
+	})
+}
+
+func Test_introspector_evict(t *testing.T) {
+	introspector := newIntrospector(IntrospectionConfig{MaxCacheEntries: 2})
+	for _, token := range []string{"a", "b", "c"} {
+		introspector.store([32]byte{token[0]}, nil)
+	}
+	assert.Len(t, introspector.cache, 2)
+}
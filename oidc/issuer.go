@@ -0,0 +1,70 @@
+package oidcverify
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bufbuild/connect-go"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"strings"
+)
+
+// An Issuer is one of the issuers accepted by the interceptor returned by
+// NewMultiIssuerOIDCInterceptor.
+type Issuer struct {
+	// URL must match the iss claim of the tokens of the issuer.
+	URL string
+	// Verifier verifies the tokens of the issuer, including their audience.
+	Verifier *oidc.IDTokenVerifier
+	// Options are applied to the tokens of the issuer after the options common
+	// to all the issuers. WithPublicProcedures has no effect here.
+	Options []option
+	// RoleMapping renames the roles of the tokens of the issuer before they are
+	// checked by WithRole. Roles that are not mapped are kept.
+	RoleMapping map[string]string
+	// AccessTokens verifies the tokens of the issuer as access tokens like
+	// NewAccessTokenInterceptor instead of ID tokens.
+	AccessTokens bool
+}
+
+// NewMultiIssuerOIDCInterceptor is like NewOIDCInterceptor but accepts tokens
+// from several issuers. The issuer is chosen from the unverified iss claim of
+// the token; tokens of unknown issuers fail with connect.CodeUnauthenticated.
+func NewMultiIssuerOIDCInterceptor(issuers []Issuer, options ...option) connect.Interceptor {
+	byURL := make(map[string]Issuer, len(issuers))
+	for _, issuer := range issuers {
+		byURL[issuer.URL] = issuer
+	}
+	return newInterceptor(func(ctx context.Context, rawToken string) (*verifiedToken, error) {
+		url, err := unverifiedIssuer(rawToken)
+		if err != nil {
+			return nil, connect.NewError(connect.CodeUnauthenticated, err)
+		}
+		issuer, ok := byURL[url]
+		if !ok {
+			return nil, connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("unknown issuer %q", url))
+		}
+		return verify(ctx, rawToken, issuer)
+	}, options)
+}
+
+// unverifiedIssuer returns the iss claim of the token without verifying it.
+func unverifiedIssuer(rawToken string) (string, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("malformed token payload: %w", err)
+	}
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("malformed token claims: %w", err)
+	}
+	return claims.Issuer, nil
+}
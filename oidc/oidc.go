@@ -2,7 +2,6 @@ package oidcverify
 
 import (
 	"context"
-	"fmt"
 	"github.com/bufbuild/connect-go"
 	"github.com/hadrienk/connect-go-interceptors/common"
 	"net/http"
@@ -10,10 +9,48 @@ import (
 )
 import "github.com/coreos/go-oidc/v3/oidc"
 
-type option func(token *oidc.IDToken) error
+type option struct {
+	// authorize is called with the verified tokens, if not nil.
+	authorize func(token *verifiedToken) error
+	// public are the patterns of the procedures that are not authenticated.
+	public []string
+	// principalRoles returns the roles of the common.Principal, if not nil.
+	principalRoles RoleExtractor
+}
+
+// verifiedToken is a token verified by one of the interceptors, with the
+// settings of its issuer.
+type verifiedToken struct {
+	ctx               context.Context
+	procedure         string
+	scheme            string
+	rawToken          string
+	header            http.Header
+	idToken           *oidc.IDToken
+	accessToken       *AccessToken
+	introspectedToken *IntrospectedToken
+	roleMapping       map[string]string
+	issuerOptions     []option
+	dpopVerified      bool
+
+	decodedClaims map[string]any
+	claimsErr     error
+}
+
+// claims returns the claims of the token. They are decoded once per call.
+func (t *verifiedToken) claims() (map[string]any, error) {
+	if t.decodedClaims == nil && t.claimsErr == nil {
+		t.claimsErr = t.idToken.Claims(&t.decodedClaims)
+	}
+	return t.decodedClaims, t.claimsErr
+}
 
 type oidcTokenKey struct{}
 
+type rawTokenKey struct{}
+
+// GetToken returns the ID token of the call verified by the interceptor. Use
+// common.PrincipalFromContext to not depend on go-oidc.
 func GetToken(ctx context.Context) (*oidc.IDToken, bool) {
 	value := ctx.Value(oidcTokenKey{})
 	if idToken, ok := value.(*oidc.IDToken); ok {
@@ -22,44 +59,130 @@ func GetToken(ctx context.Context) (*oidc.IDToken, bool) {
 	return nil, false
 }
 
-// Validate that the given role is present in the claims of the oidc.IDToken.
-func WithRole(role string) option {
-	return WithHandler(func(token *oidc.IDToken) error {
-		var claims struct {
-			Roles []string `json:"roles"`
+// GetRawToken returns the raw token of the call verified by one of the server
+// interceptors, e.g. the one returned by NewOIDCInterceptor.
+func GetRawToken(ctx context.Context) (string, bool) {
+	rawToken, ok := ctx.Value(rawTokenKey{}).(string)
+	return rawToken, ok
+}
+
+// Add a custom handler that gets called with the validated oidc.IDToken. The
+// tokens verified by introspection only have the standard claims set.
+func WithHandler(handler func(token *oidc.IDToken) error) option {
+	return option{authorize: func(token *verifiedToken) error {
+		return handler(token.idToken)
+	}}
+}
+
+func NewOIDCInterceptor(verifier *oidc.IDTokenVerifier, options ...option) connect.Interceptor {
+	return newInterceptor(func(ctx context.Context, rawToken string) (*verifiedToken, error) {
+		return verify(ctx, rawToken, Issuer{Verifier: verifier})
+	}, options)
+}
+
+// newInterceptor returns a server interceptor that authenticates the bearer
+// tokens of the calls with the authenticate function and authorizes them with
+// the options, except for the calls of the public procedures.
+func newInterceptor(authenticate func(ctx context.Context, rawToken string) (*verifiedToken, error), options []option) connect.Interceptor {
+	var public common.ProcedurePatterns
+	principalRoles := RolesAt("roles")
+	for _, option := range options {
+		public = append(public, option.public...)
+		if option.principalRoles != nil {
+			principalRoles = option.principalRoles
 		}
-		if err := token.Claims(&claims); err != nil {
-			return connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("no roles claim", err))
+	}
+	return common.ContextSpecHeaderInterceptor(func(ctx context.Context, spec connect.Spec, header http.Header) (context.Context, error) {
+		if _, ok := public.Match(spec.Procedure); ok {
+			return ctx, nil
 		}
-		for _, r := range claims.Roles {
-			if strings.TrimSpace(r) == strings.TrimSpace(role) {
-				return nil
-			}
+		scheme, rawToken := authorizationToken(header)
+		token, err := authenticate(ctx, rawToken)
+		if err != nil {
+			return ctx, err
 		}
-		return connect.NewError(connect.CodePermissionDenied, fmt.Errorf("missing role %s", role))
+		token.ctx, token.procedure = ctx, spec.Procedure
+		token.scheme, token.rawToken, token.header = scheme, rawToken, header
+		ctx, err = authorize(ctx, rawToken, token, options)
+		if err != nil {
+			return ctx, err
+		}
+		return common.ContextWithPrincipal(ctx, token.principal(principalRoles)), nil
 	})
 }
 
-// Add a custom handler that gets called with the validated oidc.IDToken
-func WithHandler(handler func(token *oidc.IDToken) error) option {
-	return option(handler)
+// authorizationToken returns the scheme, Bearer or DPoP, and the token of the
+// Authorization header.
+func authorizationToken(header http.Header) (string, string) {
+	authorization := strings.TrimSpace(header.Get("Authorization"))
+	for _, scheme := range []string{"Bearer", "DPoP"} {
+		if strings.HasPrefix(authorization, scheme) {
+			return scheme, strings.TrimSpace(strings.TrimPrefix(authorization, scheme))
+		}
+	}
+	return "", authorization
 }
 
-func NewOIDCInterceptor(verifier *oidc.IDTokenVerifier, options ...option) connect.Interceptor {
-	return common.ContextHeaderInterceptor(func(ctx context.Context, header http.Header) (context.Context, error) {
-		rawToken := header.Get("Authorization")
-		rawToken = strings.TrimPrefix(rawToken, "Bearer")
-		rawToken = strings.TrimSpace(rawToken)
-		idToken, err := verifier.Verify(ctx, rawToken)
+// verify verifies the token with the issuer.
+func verify(ctx context.Context, rawToken string, issuer Issuer) (*verifiedToken, error) {
+	idToken, err := issuer.Verifier.Verify(ctx, rawToken)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnauthenticated, err)
+	}
+	token := &verifiedToken{idToken: idToken, roleMapping: issuer.RoleMapping, issuerOptions: issuer.Options}
+	if issuer.AccessTokens {
+		token.accessToken, err = verifyAccessToken(rawToken, token)
 		if err != nil {
-			return ctx, connect.NewError(connect.CodeUnauthenticated, err)
+			return nil, connect.NewError(connect.CodeUnauthenticated, err)
 		}
+	}
+	return token, nil
+}
+
+// principal returns the common.Principal of the token with the roles returned
+// by the extractor, renamed by the role mapping.
+func (t *verifiedToken) principal(extractor RoleExtractor) *common.Principal {
+	claims, _ := t.claims()
+	principal := &common.Principal{
+		Subject:    t.idToken.Subject,
+		Issuer:     t.idToken.Issuer,
+		Claims:     claims,
+		AuthMethod: common.AuthMethodOIDC,
+	}
+	if t.accessToken != nil || t.introspectedToken != nil {
+		principal.AuthMethod = common.AuthMethodOAuth2
+	}
+	for _, role := range extractor(claims) {
+		principal.Roles = append(principal.Roles, t.mapRole(role))
+	}
+	principal.Scopes, _ = t.scopes()
+	return principal
+}
+
+// authorize applies the options, then the options of the issuer, to the token
+// and adds it to the context.
+func authorize(ctx context.Context, rawToken string, token *verifiedToken, options []option) (context.Context, error) {
+	for _, options := range [][]option{options, token.issuerOptions} {
 		for _, option := range options {
-			err := option(idToken)
+			if option.authorize == nil {
+				continue
+			}
+			err := option.authorize(token)
 			if err != nil {
 				return ctx, err
 			}
 		}
-		return context.WithValue(ctx, oidcTokenKey{}, idToken), nil
-	})
+	}
+	if err := checkDPoP(token); err != nil {
+		return ctx, err
+	}
+	ctx = context.WithValue(ctx, rawTokenKey{}, rawToken)
+	switch {
+	case token.accessToken != nil:
+		return context.WithValue(ctx, accessTokenKey{}, token.accessToken), nil
+	case token.introspectedToken != nil:
+		return context.WithValue(ctx, introspectedTokenKey{}, token.introspectedToken), nil
+	default:
+		return context.WithValue(ctx, oidcTokenKey{}, token.idToken), nil
+	}
 }
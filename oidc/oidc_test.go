@@ -0,0 +1,171 @@
+package oidcverify
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"github.com/bufbuild/connect-go"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type testIssuer struct {
+	url          string
+	key          *rsa.PrivateKey
+	signer       jose.Signer
+	accessSigner jose.Signer
+}
+
+func newTestIssuer(t *testing.T, url string) *testIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, nil)
+	require.NoError(t, err)
+	accessSigner, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, (&jose.SignerOptions{}).WithType("at+jwt"))
+	require.NoError(t, err)
+	return &testIssuer{url: url, key: key, signer: signer, accessSigner: accessSigner}
+}
+
+func (i *testIssuer) verifier(audience string) *oidc.IDTokenVerifier {
+	keySet := &oidc.StaticKeySet{PublicKeys: []crypto.PublicKey{i.key.Public()}}
+	return oidc.NewVerifier(i.url, keySet, &oidc.Config{ClientID: audience})
+}
+
+// token signs a token of the issuer with the given claims on top of the
+// iss, aud and exp claims.
+func (i *testIssuer) token(t *testing.T, audience string, claims map[string]any) string {
+	return i.sign(t, i.signer, audience, claims)
+}
+
+// accessToken signs an access token of the issuer with the at+jwt type.
+func (i *testIssuer) accessToken(t *testing.T, audience string, claims map[string]any) string {
+	return i.sign(t, i.accessSigner, audience, claims)
+}
+
+func (i *testIssuer) sign(t *testing.T, signer jose.Signer, audience string, claims map[string]any) string {
+	payload := map[string]any{
+		"iss": i.url,
+		"aud": audience,
+		"sub": "subject",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range claims {
+		payload[name] = value
+	}
+	bytes, err := json.Marshal(payload)
+	require.NoError(t, err)
+	signature, err := signer.Sign(bytes)
+	require.NoError(t, err)
+	token, err := signature.CompactSerialize()
+	require.NoError(t, err)
+	return token
+}
+
+type serverRequest struct {
+	*connect.Request[msg]
+	procedure string
+}
+
+func (s serverRequest) Spec() connect.Spec {
+	return connect.Spec{Procedure: s.procedure}
+}
+
+// call calls a handler wrapped by the interceptor with the token and returns
+// the context of the handler.
+func call(interceptor connect.Interceptor, token string) (context.Context, error) {
+	return callProcedure(interceptor, "/acme.foo.v1.FooService/Bar", token)
+}
+
+func callProcedure(interceptor connect.Interceptor, procedure, token string) (context.Context, error) {
+	var handlerCtx context.Context
+	request := serverRequest{Request: connect.NewRequest(&msg{}), procedure: procedure}
+	if token != "" {
+		request.Header().Set("Authorization", "Bearer "+token)
+	}
+	_, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		handlerCtx = ctx
+		return connect.NewResponse(&msg{}), nil
+	})(context.Background(), request)
+	return handlerCtx, err
+}
+
+func TestNewOIDCInterceptor(t *testing.T) {
+	issuer := newTestIssuer(t, "https://idp.example.com")
+	interceptor := NewOIDCInterceptor(issuer.verifier("api"), WithRole("admin"))
+
+	t.Run("verifies the token", func(t *testing.T) {
+		token := issuer.token(t, "api", map[string]any{"roles": []string{"admin"}})
+		ctx, err := call(interceptor, token)
+		require.NoError(t, err)
+		idToken, ok := GetToken(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, "subject", idToken.Subject)
+		rawToken, ok := GetRawToken(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, token, rawToken)
+	})
+
+	t.Run("rejects invalid tokens", func(t *testing.T) {
+		_, err := call(interceptor, issuer.token(t, "other", map[string]any{"roles": []string{"admin"}}))
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+
+	t.Run("checks the roles", func(t *testing.T) {
+		_, err := call(interceptor, issuer.token(t, "api", map[string]any{"roles": []string{"user"}}))
+		assert.Equal(t, connect.CodePermissionDenied, connect.CodeOf(err))
+	})
+}
+
+func TestNewMultiIssuerOIDCInterceptor(t *testing.T) {
+	corporate := newTestIssuer(t, "https://corporate.example.com")
+	partner := newTestIssuer(t, "https://partner.example.com")
+	unknown := newTestIssuer(t, "https://unknown.example.com")
+	interceptor := NewMultiIssuerOIDCInterceptor([]Issuer{
+		{URL: corporate.url, Verifier: corporate.verifier("api")},
+		{
+			URL:         partner.url,
+			Verifier:    partner.verifier("partner-api"),
+			RoleMapping: map[string]string{"Administrator": "admin"},
+		},
+	}, WithRole("admin"))
+
+	t.Run("verifies the tokens of each issuer", func(t *testing.T) {
+		_, err := call(interceptor, corporate.token(t, "api", map[string]any{"roles": []string{"admin"}}))
+		assert.NoError(t, err)
+		_, err = call(interceptor, partner.token(t, "partner-api", map[string]any{"roles": []string{"Administrator"}}))
+		assert.NoError(t, err)
+	})
+
+	t.Run("uses the audience of the issuer", func(t *testing.T) {
+		_, err := call(interceptor, partner.token(t, "api", map[string]any{"roles": []string{"Administrator"}}))
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+
+	t.Run("uses the role mapping of the issuer", func(t *testing.T) {
+		_, err := call(interceptor, corporate.token(t, "api", map[string]any{"roles": []string{"Administrator"}}))
+		assert.Equal(t, connect.CodePermissionDenied, connect.CodeOf(err))
+	})
+
+	t.Run("rejects unknown issuers", func(t *testing.T) {
+		_, err := call(interceptor, unknown.token(t, "api", map[string]any{"roles": []string{"admin"}}))
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+		assert.ErrorContains(t, err, `unknown issuer "https://unknown.example.com"`)
+	})
+
+	t.Run("rejects tokens signed by another issuer", func(t *testing.T) {
+		token := unknown.token(t, "api", map[string]any{"iss": corporate.url, "roles": []string{"admin"}})
+		_, err := call(interceptor, token)
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+
+	t.Run("rejects malformed tokens", func(t *testing.T) {
+		_, err := call(interceptor, "malformed")
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+}
@@ -0,0 +1,43 @@
+package oidcverify
+
+import "github.com/hadrienk/connect-go-interceptors/common"
+
+// A Policy maps procedures to the options that authorize their calls, on top
+// of the options applying to all the calls. The keys are procedures like
+// /acme.foo.v1.FooService/Bar or service prefixes ending with /* like
+// /acme.admin.v1.AdminService/*.
+type Policy map[string][]option
+
+// WithPolicy applies the options of the most specific key of the policy
+// matching the procedure of the calls: the procedure itself, then the longest
+// prefix. The calls of the procedures without key only get the other options.
+func WithPolicy(policy Policy) option {
+	keys := make([]string, 0, len(policy))
+	for key := range policy {
+		keys = append(keys, key)
+	}
+	patterns := common.NewProcedurePatterns(keys...)
+	return option{authorize: func(token *verifiedToken) error {
+		pattern, ok := patterns.Match(token.procedure)
+		if !ok {
+			return nil
+		}
+		for _, option := range policy[pattern] {
+			if option.authorize == nil {
+				continue
+			}
+			if err := option.authorize(token); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}
+}
+
+// WithPublicProcedures lets the calls of the procedures matching the patterns
+// through without authentication, e.g. health checks and reflection. The
+// patterns are like the keys of a Policy. The calls have no token in their
+// context.
+func WithPublicProcedures(patterns ...string) option {
+	return option{public: patterns}
+}
@@ -0,0 +1,51 @@
+package oidcverify
+
+import (
+	"github.com/bufbuild/connect-go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWithPolicy(t *testing.T) {
+	issuer := newTestIssuer(t, "https://idp.example.com")
+	interceptor := NewOIDCInterceptor(issuer.verifier("api"),
+		WithPolicy(Policy{
+			"/acme.admin.v1.AdminService/*":         {WithRole("admin")},
+			"/acme.admin.v1.AdminService/ListUsers": {WithScope("users:read")},
+			"/acme.foo.v1.FooService/Delete":        {WithRole("admin"), WithScope("foo:delete")},
+		}),
+		WithPublicProcedures("/grpc.health.v1.Health/*", "/acme.foo.v1.FooService/Ping"),
+	)
+	user := issuer.token(t, "api", map[string]any{"roles": []string{"user"}, "scope": "users:read"})
+	admin := issuer.token(t, "api", map[string]any{"roles": []string{"admin"}})
+
+	tests := []struct {
+		name      string
+		procedure string
+		token     string
+		want      connect.Code
+	}{
+		{"applies no options to other procedures", "/acme.foo.v1.FooService/Bar", user, 0},
+		{"authenticates other procedures", "/acme.foo.v1.FooService/Bar", "", connect.CodeUnauthenticated},
+		{"applies the options of the procedure", "/acme.foo.v1.FooService/Delete", admin, connect.CodePermissionDenied},
+		{"applies the options of the prefix", "/acme.admin.v1.AdminService/DeleteUser", user, connect.CodePermissionDenied},
+		{"accepts the options of the prefix", "/acme.admin.v1.AdminService/DeleteUser", admin, 0},
+		{"applies the most specific options", "/acme.admin.v1.AdminService/ListUsers", user, 0},
+		{"skips public prefixes", "/grpc.health.v1.Health/Check", "", 0},
+		{"skips public procedures", "/acme.foo.v1.FooService/Ping", "invalid", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := callProcedure(interceptor, tt.procedure, tt.token)
+			if tt.want == 0 {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.want, connect.CodeOf(err))
+			}
+			if tt.token == "" && err == nil {
+				_, ok := GetToken(ctx)
+				assert.False(t, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package oidcverify
+
+import (
+	"github.com/hadrienk/connect-go-interceptors/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPrincipal(t *testing.T) {
+	issuer := newTestIssuer(t, "https://idp.example.com")
+
+	t.Run("adds the principal of ID tokens", func(t *testing.T) {
+		interceptor := NewMultiIssuerOIDCInterceptor([]Issuer{{
+			URL:         issuer.url,
+			Verifier:    issuer.verifier("api"),
+			RoleMapping: map[string]string{"Administrator": "admin"},
+		}})
+		ctx, err := call(interceptor, issuer.token(t, "api", map[string]any{
+			"roles": []string{"Administrator", "user"},
+			"scope": "read",
+		}))
+		require.NoError(t, err)
+		principal, ok := common.PrincipalFromContext(ctx)
+		require.True(t, ok)
+		assert.Equal(t, "subject", principal.Subject)
+		assert.Equal(t, issuer.url, principal.Issuer)
+		assert.Equal(t, []string{"admin", "user"}, principal.Roles)
+		assert.Equal(t, []string{"read"}, principal.Scopes)
+		assert.Equal(t, "read", principal.Claims["scope"])
+		assert.Equal(t, common.AuthMethodOIDC, principal.AuthMethod)
+	})
+
+	t.Run("adds the principal of access tokens", func(t *testing.T) {
+		interceptor := NewAccessTokenInterceptor(issuer.verifier("api"), WithPrincipalRoles(KeycloakRealmRoles()))
+		ctx, err := call(interceptor, issuer.accessToken(t, "api", map[string]any{
+			"client_id":    "client",
+			"realm_access": map[string]any{"roles": []string{"admin"}},
+		}))
+		require.NoError(t, err)
+		principal, ok := common.PrincipalFromContext(ctx)
+		require.True(t, ok)
+		assert.Equal(t, []string{"admin"}, principal.Roles)
+		assert.Equal(t, common.AuthMethodOAuth2, principal.AuthMethod)
+	})
+}
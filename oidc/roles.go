@@ -0,0 +1,106 @@
+package oidcverify
+
+import (
+	"fmt"
+	"github.com/bufbuild/connect-go"
+	"strings"
+)
+
+// A RoleExtractor returns the roles found in the claims of a token.
+type RoleExtractor func(claims map[string]any) []string
+
+// RolesAt returns the roles of the claim at the dot-separated path, e.g. roles,
+// groups or realm_access.roles. The claim can be a string or an array of
+// strings. Use RolesAtPath when the keys contain dots.
+func RolesAt(path string) RoleExtractor {
+	return RolesAtPath(strings.Split(path, ".")...)
+}
+
+// RolesAtPath is like RolesAt with the keys of the path given separately.
+func RolesAtPath(keys ...string) RoleExtractor {
+	return func(claims map[string]any) []string {
+		var value any = claims
+		for _, key := range keys {
+			object, ok := value.(map[string]any)
+			if !ok {
+				return nil
+			}
+			value = object[key]
+		}
+		return stringValues(value, func(s string) []string {
+			return []string{s}
+		})
+	}
+}
+
+// KeycloakRealmRoles returns the realm roles of the Keycloak tokens.
+func KeycloakRealmRoles() RoleExtractor {
+	return RolesAt("realm_access.roles")
+}
+
+// KeycloakClientRoles returns the roles of the client of the Keycloak tokens.
+func KeycloakClientRoles(clientID string) RoleExtractor {
+	return RolesAtPath("resource_access", clientID, "roles")
+}
+
+// Validate that the given role is present in the claims of the oidc.IDToken.
+func WithRole(role string) option {
+	return WithRoleFrom(RolesAt("roles"), role)
+}
+
+// WithRoleAt validates that the given role is present in the claim at the
+// dot-separated path, e.g. groups or realm_access.roles.
+func WithRoleAt(path string, role string) option {
+	return WithRoleFrom(RolesAt(path), role)
+}
+
+// WithRoleFrom validates that the given role is one of the roles returned by
+// the extractor. The roles are renamed by the RoleMapping of the issuer first.
+func WithRoleFrom(extractor RoleExtractor, role string) option {
+	return option{authorize: func(token *verifiedToken) error {
+		claims, err := token.claims()
+		if err != nil {
+			return connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("no roles claim: %w", err))
+		}
+		for _, r := range extractor(claims) {
+			if token.mapRole(r) == strings.TrimSpace(role) {
+				return nil
+			}
+		}
+		return connect.NewError(connect.CodePermissionDenied, fmt.Errorf("missing role %s", role))
+	}}
+}
+
+// WithPrincipalRoles sets the extractor of the roles of the common.Principal
+// added to the context. Defaults to RolesAt("roles").
+func WithPrincipalRoles(extractor RoleExtractor) option {
+	return option{principalRoles: extractor}
+}
+
+// mapRole renames the role with the role mapping of the issuer.
+func (t *verifiedToken) mapRole(role string) string {
+	role = strings.TrimSpace(role)
+	if mapped, ok := t.roleMapping[role]; ok {
+		return mapped
+	}
+	return role
+}
+
+// stringValues returns the strings of an array claim, or the strings split
+// from a string claim.
+func stringValues(value any, split func(string) []string) []string {
+	switch value := value.(type) {
+	case string:
+		return split(value)
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
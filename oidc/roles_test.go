@@ -0,0 +1,68 @@
+package oidcverify
+
+import (
+	"github.com/bufbuild/connect-go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRolesAt(t *testing.T) {
+	claims := map[string]any{
+		"roles":  []any{"admin", "user"},
+		"groups": "admins",
+		"realm_access": map[string]any{
+			"roles": []any{"realm-admin"},
+		},
+		"resource_access": map[string]any{
+			"my.client": map[string]any{
+				"roles": []any{"client-admin"},
+			},
+		},
+	}
+	assert.Equal(t, []string{"admin", "user"}, RolesAt("roles")(claims))
+	assert.Equal(t, []string{"admins"}, RolesAt("groups")(claims))
+	assert.Equal(t, []string{"realm-admin"}, KeycloakRealmRoles()(claims))
+	assert.Equal(t, []string{"client-admin"}, KeycloakClientRoles("my.client")(claims))
+	assert.Nil(t, RolesAt("missing.roles")(claims))
+	assert.Nil(t, RolesAt("roles.nested")(claims))
+}
+
+func TestWithRoleFrom(t *testing.T) {
+	issuer := newTestIssuer(t, "https://idp.example.com")
+	token := issuer.token(t, "api", map[string]any{
+		"groups": "admins",
+		"realm_access": map[string]any{
+			"roles": []string{"realm-admin"},
+		},
+		"resource_access": map[string]any{
+			"api": map[string]any{
+				"roles": []string{"client-admin"},
+			},
+		},
+	})
+
+	tests := []struct {
+		name   string
+		option option
+		want   connect.Code
+	}{
+		{"reads string claims", WithRoleAt("groups", "admins"), 0},
+		{"reads nested claims", WithRoleAt("realm_access.roles", "realm-admin"), 0},
+		{"reads client roles", WithRoleFrom(KeycloakClientRoles("api"), "client-admin"), 0},
+		{"rejects missing roles", WithRoleFrom(KeycloakRealmRoles(), "client-admin"), connect.CodePermissionDenied},
+		{"rejects missing claims", WithRole("admins"), connect.CodePermissionDenied},
+		{"accepts custom extractors", WithRoleFrom(func(claims map[string]any) []string {
+			return []string{claims["sub"].(string)}
+		}, "subject"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := call(NewOIDCInterceptor(issuer.verifier("api"), tt.option), token)
+			if tt.want == 0 {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.want, connect.CodeOf(err))
+			}
+		})
+	}
+}
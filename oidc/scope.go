@@ -0,0 +1,93 @@
+package oidcverify
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bufbuild/connect-go"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"strings"
+)
+
+// MissingScopeReason is the reason of the errdetails.ErrorInfo detail of the
+// errors returned when a scope is missing. Its missing_scopes metadata lists
+// the missing scopes separated by spaces.
+const MissingScopeReason = "MISSING_SCOPE"
+
+// scopes returns the scopes of the token from the space-delimited scope claim
+// or the scp claim.
+func (t *verifiedToken) scopes() ([]string, error) {
+	if t.accessToken != nil {
+		return t.accessToken.Scopes, nil
+	}
+	claims, err := t.claims()
+	if err != nil {
+		return nil, err
+	}
+	return scopes(claims), nil
+}
+
+// Validate that the given scope is present in the scope or scp claim of the token.
+func WithScope(scope string) option {
+	return WithAllScopes(scope)
+}
+
+// Validate that at least one of the given scopes is present in the scope or
+// scp claim of the token.
+func WithAnyScope(scopes ...string) option {
+	return option{authorize: func(token *verifiedToken) error {
+		granted, err := token.scopes()
+		if err != nil {
+			return connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("no scope claim: %w", err))
+		}
+		for _, scope := range scopes {
+			if contains(granted, scope) {
+				return nil
+			}
+		}
+		return missingScopesError(fmt.Sprintf("missing one of scopes %s", strings.Join(scopes, ", ")), scopes)
+	}}
+}
+
+// Validate that all the given scopes are present in the scope or scp claim of
+// the token.
+func WithAllScopes(scopes ...string) option {
+	return option{authorize: func(token *verifiedToken) error {
+		granted, err := token.scopes()
+		if err != nil {
+			return connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("no scope claim: %w", err))
+		}
+		var missing []string
+		for _, scope := range scopes {
+			if !contains(granted, scope) {
+				missing = append(missing, scope)
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		return missingScopesError(fmt.Sprintf("missing scopes %s", strings.Join(missing, ", ")), missing)
+	}}
+}
+
+// missingScopesError returns a connect.CodePermissionDenied error with an
+// errdetails.ErrorInfo detail listing the missing scopes.
+func missingScopesError(message string, missing []string) error {
+	err := connect.NewError(connect.CodePermissionDenied, errors.New(message))
+	detail, detailErr := connect.NewErrorDetail(&errdetails.ErrorInfo{
+		Reason:   MissingScopeReason,
+		Metadata: map[string]string{"missing_scopes": strings.Join(missing, " ")},
+	})
+	if detailErr == nil {
+		err.AddDetail(detail)
+	}
+	return err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package oidcverify
+
+import (
+	"errors"
+	"github.com/bufbuild/connect-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"testing"
+)
+
+func TestWithScope(t *testing.T) {
+	issuer := newTestIssuer(t, "https://idp.example.com")
+	token := issuer.token(t, "api", map[string]any{"scope": "read write"})
+	scpToken := issuer.token(t, "api", map[string]any{"scp": []string{"read", "write"}})
+
+	tests := []struct {
+		name   string
+		option option
+		want   connect.Code
+	}{
+		{"accepts the scope", WithScope("read"), 0},
+		{"rejects missing scope", WithScope("admin"), connect.CodePermissionDenied},
+		{"accepts any scope", WithAnyScope("admin", "write"), 0},
+		{"rejects none of the scopes", WithAnyScope("admin", "delete"), connect.CodePermissionDenied},
+		{"accepts all the scopes", WithAllScopes("read", "write"), 0},
+		{"rejects some of the scopes", WithAllScopes("read", "admin"), connect.CodePermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interceptor := NewOIDCInterceptor(issuer.verifier("api"), tt.option)
+			for _, token := range []string{token, scpToken} {
+				_, err := call(interceptor, token)
+				if tt.want == 0 {
+					assert.NoError(t, err)
+				} else {
+					assert.Equal(t, tt.want, connect.CodeOf(err))
+				}
+			}
+		})
+	}
+
+	t.Run("details the missing scopes", func(t *testing.T) {
+		interceptor := NewOIDCInterceptor(issuer.verifier("api"), WithAllScopes("read", "admin", "delete"))
+		_, err := call(interceptor, token)
+		var connectErr *connect.Error
+		require.True(t, errors.As(err, &connectErr))
+		require.Len(t, connectErr.Details(), 1)
+		value, err := connectErr.Details()[0].Value()
+		require.NoError(t, err)
+		info, ok := value.(*errdetails.ErrorInfo)
+		require.True(t, ok)
+		assert.Equal(t, MissingScopeReason, info.Reason)
+		assert.Equal(t, "admin delete", info.Metadata["missing_scopes"])
+	})
+}
@@ -0,0 +1,184 @@
+package otel
+
+import (
+	"context"
+	"github.com/bufbuild/connect-go"
+	"github.com/hadrienk/connect-go-interceptors/common"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+const instrumentationName = "github.com/hadrienk/connect-go-interceptors/otel"
+
+var connectErrorCodeKey = attribute.Key("rpc.connect_rpc.error_code")
+
+// An Option configures the tracing interceptor.
+type Option func(*interceptor)
+
+// WithTracerProvider sets the trace.TracerProvider used to create the spans.
+// Defaults to the global provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(i *interceptor) {
+		i.provider = provider
+	}
+}
+
+// WithPropagator sets the propagation.TextMapPropagator used to extract and
+// inject the span context from and into the headers. Defaults to W3C trace context.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(i *interceptor) {
+		i.propagator = propagator
+	}
+}
+
+type interceptor struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+	tracer     trace.Tracer
+}
+
+// NewInterceptor returns a connect.Interceptor that creates a span for every RPC.
+// Handlers create server spans that continue the trace found in the request
+// headers, clients create client spans and inject their context in the request
+// headers. Every message sent or received is recorded as a span event.
+func NewInterceptor(options ...Option) connect.Interceptor {
+	i := &interceptor{
+		provider:   otel.GetTracerProvider(),
+		propagator: propagation.TraceContext{},
+	}
+	for _, option := range options {
+		option(i)
+	}
+	i.tracer = i.provider.Tracer(instrumentationName)
+	return i
+}
+
+func (i *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		spec := request.Spec()
+		if spec.IsClient {
+			ctx, span := i.start(ctx, spec, request.Peer())
+			defer span.End()
+			i.propagator.Inject(ctx, propagation.HeaderCarrier(request.Header()))
+			messageEvent(span, semconv.MessageTypeSent, 1)
+			response, err := next(ctx, request)
+			if err == nil {
+				messageEvent(span, semconv.MessageTypeReceived, 1)
+			}
+			setStatus(span, spec, request.Peer(), err)
+			return response, err
+		}
+		ctx = i.propagator.Extract(ctx, propagation.HeaderCarrier(request.Header()))
+		ctx, span := i.start(ctx, spec, request.Peer())
+		defer span.End()
+		messageEvent(span, semconv.MessageTypeReceived, 1)
+		response, err := next(ctx, request)
+		if err == nil {
+			messageEvent(span, semconv.MessageTypeSent, 1)
+		}
+		setStatus(span, spec, request.Peer(), err)
+		return response, err
+	}
+}
+
+func (i *interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		ctx, span := i.start(ctx, spec, connect.Peer{})
+		conn := next(ctx, spec)
+		span.SetAttributes(peerAttributes(conn.Peer())...)
+		i.propagator.Inject(ctx, propagation.HeaderCarrier(conn.RequestHeader()))
+		return &tracingClient{
+			StreamingClientConn: conn,
+			span:                span,
+		}
+	}
+}
+
+func (i *interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx = i.propagator.Extract(ctx, propagation.HeaderCarrier(conn.RequestHeader()))
+		ctx, span := i.start(ctx, conn.Spec(), conn.Peer())
+		defer span.End()
+		err := next(ctx, &tracingHandler{
+			StreamingHandlerConn: conn,
+			span:                 span,
+		})
+		setStatus(span, conn.Spec(), conn.Peer(), err)
+		return err
+	}
+}
+
+func (i *interceptor) start(ctx context.Context, spec connect.Spec, peer connect.Peer) (context.Context, trace.Span) {
+	kind := trace.SpanKindServer
+	if spec.IsClient {
+		kind = trace.SpanKindClient
+	}
+	name := strings.TrimPrefix(spec.Procedure, "/")
+	service, method := common.SplitProcedure(spec.Procedure)
+	attributes := append([]attribute.KeyValue{
+		semconv.RPCService(service),
+		semconv.RPCMethod(method),
+	}, peerAttributes(peer)...)
+	return i.tracer.Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attributes...))
+}
+
+func peerAttributes(peer connect.Peer) []attribute.KeyValue {
+	var attributes []attribute.KeyValue
+	switch peer.Protocol {
+	case "":
+	case connect.ProtocolConnect:
+		attributes = append(attributes, semconv.RPCSystemConnectRPC)
+	default:
+		attributes = append(attributes, semconv.RPCSystemGRPC)
+	}
+	if peer.Addr != "" {
+		attributes = append(attributes, semconv.NetSockPeerAddr(peer.Addr))
+	}
+	return attributes
+}
+
+func messageEvent(span trace.Span, messageType attribute.KeyValue, id int) {
+	span.AddEvent("message", trace.WithAttributes(messageType, semconv.MessageID(id)))
+}
+
+// setStatus records the connect code of the error on the span. Following the
+// OpenTelemetry RPC conventions, clients mark every error as a span error while
+// handlers only do so for codes that indicate a server failure.
+func setStatus(span trace.Span, spec connect.Spec, peer connect.Peer, err error) {
+	code := connect.Code(0)
+	if err != nil {
+		code = connect.CodeOf(err)
+	}
+	if peer.Protocol == connect.ProtocolConnect {
+		if err != nil {
+			span.SetAttributes(connectErrorCodeKey.String(code.String()))
+		}
+	} else {
+		span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	}
+	if err == nil {
+		return
+	}
+	if spec.IsClient || isServerError(code) {
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+func isServerError(code connect.Code) bool {
+	switch code {
+	case connect.CodeUnknown,
+		connect.CodeDeadlineExceeded,
+		connect.CodeUnimplemented,
+		connect.CodeInternal,
+		connect.CodeUnavailable,
+		connect.CodeDataLoss:
+		return true
+	default:
+		return false
+	}
+}
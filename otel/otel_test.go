@@ -0,0 +1,284 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"github.com/bufbuild/connect-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"net/http"
+	"testing"
+)
+
+const (
+	traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	procedure   = "/foo.v1.FooService/Bar"
+)
+
+type msg struct {
+}
+
+func newInterceptor() (connect.Interceptor, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return NewInterceptor(WithTracerProvider(provider)), exporter
+}
+
+func messageEvents(span tracetest.SpanStub) []string {
+	var events []string
+	for _, event := range span.Events {
+		for _, kv := range event.Attributes {
+			if kv.Key == semconv.MessageTypeKey {
+				events = append(events, kv.Value.AsString())
+			}
+		}
+	}
+	return events
+}
+
+func attributeValue(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+type specRequest struct {
+	*connect.Request[msg]
+	spec connect.Spec
+}
+
+func (s *specRequest) Spec() connect.Spec {
+	return s.spec
+}
+
+func TestInterceptor_WrapUnary(t *testing.T) {
+	t.Run("server span continues the remote trace", func(t *testing.T) {
+		interceptor, exporter := newInterceptor()
+		request := &specRequest{Request: connect.NewRequest(&msg{}), spec: connect.Spec{Procedure: procedure}}
+		request.Header().Set("traceparent", traceParent)
+
+		_, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			assert.True(t, trace.SpanContextFromContext(ctx).IsValid())
+			return connect.NewResponse(&msg{}), nil
+		})(context.Background(), request)
+		require.NoError(t, err)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "foo.v1.FooService/Bar", spans[0].Name)
+		assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent.SpanID().String())
+		assert.Equal(t, "foo.v1.FooService", attributeValue(spans[0], semconv.RPCServiceKey).AsString())
+		assert.Equal(t, "Bar", attributeValue(spans[0], semconv.RPCMethodKey).AsString())
+		assert.Equal(t, []string{"RECEIVED", "SENT"}, messageEvents(spans[0]))
+		assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	})
+
+	t.Run("server span status depends on the code", func(t *testing.T) {
+		interceptor, exporter := newInterceptor()
+		request := &specRequest{Request: connect.NewRequest(&msg{}), spec: connect.Spec{Procedure: procedure}}
+		for _, code := range []connect.Code{connect.CodeNotFound, connect.CodeInternal} {
+			_, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+				return nil, connect.NewError(code, errors.New("error"))
+			})(context.Background(), request)
+			assert.Error(t, err)
+		}
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 2)
+		assert.Equal(t, codes.Unset, spans[0].Status.Code)
+		assert.Equal(t, int64(connect.CodeNotFound), attributeValue(spans[0], semconv.RPCGRPCStatusCodeKey).AsInt64())
+		assert.Equal(t, codes.Error, spans[1].Status.Code)
+		assert.Equal(t, int64(connect.CodeInternal), attributeValue(spans[1], semconv.RPCGRPCStatusCodeKey).AsInt64())
+	})
+
+	t.Run("client span is injected in the headers", func(t *testing.T) {
+		interceptor, exporter := newInterceptor()
+		request := &specRequest{Request: connect.NewRequest(&msg{}), spec: connect.Spec{Procedure: procedure, IsClient: true}}
+
+		_, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			return nil, connect.NewError(connect.CodeNotFound, errors.New("not found"))
+		})(context.Background(), request)
+		assert.Error(t, err)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind)
+		assert.Equal(t, "00-"+spans[0].SpanContext.TraceID().String()+"-"+spans[0].SpanContext.SpanID().String()+"-01",
+			request.Header().Get("traceparent"))
+		assert.Equal(t, []string{"SENT"}, messageEvents(spans[0]))
+		assert.Equal(t, codes.Error, spans[0].Status.Code)
+	})
+}
+
+func TestInterceptor_WrapStreamingHandler(t *testing.T) {
+	interceptor, exporter := newInterceptor()
+	conn := noOpConn{
+		spec:      connect.Spec{Procedure: procedure, StreamType: connect.StreamTypeBidi},
+		peer:      connect.Peer{Protocol: connect.ProtocolConnect, Addr: "127.0.0.1:1234"},
+		reqHeader: http.Header{"Traceparent": {traceParent}},
+	}
+	expectedError := connect.NewError(connect.CodeUnavailable, errors.New("unavailable"))
+
+	err := interceptor.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		assert.NoError(t, conn.Receive(&msg{}))
+		assert.NoError(t, conn.Receive(&msg{}))
+		assert.NoError(t, conn.Send(&msg{}))
+		return expectedError
+	})(context.Background(), conn)
+	assert.Same(t, expectedError, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID().String())
+	assert.Equal(t, []string{"RECEIVED", "RECEIVED", "SENT"}, messageEvents(spans[0]))
+	assert.Equal(t, int64(2), spans[0].Events[1].Attributes[1].Value.AsInt64())
+	assert.Equal(t, "connect_rpc", attributeValue(spans[0], semconv.RPCSystemKey).AsString())
+	assert.Equal(t, "unavailable", attributeValue(spans[0], connectErrorCodeKey).AsString())
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+}
+
+func TestInterceptor_WrapStreamingClient(t *testing.T) {
+	spec := connect.Spec{Procedure: procedure, StreamType: connect.StreamTypeServer, IsClient: true}
+
+	t.Run("ends span at the end of the stream", func(t *testing.T) {
+		interceptor, exporter := newInterceptor()
+		header := http.Header{}
+		received := 0
+		conn := interceptor.WrapStreamingClient(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+			return noOpClientConn{
+				spec:      spec,
+				reqHeader: header,
+				receive: func(msg any) error {
+					received++
+					if received > 1 {
+						return io.EOF
+					}
+					return nil
+				},
+			}
+		})(context.Background(), spec)
+
+		assert.NoError(t, conn.Send(&msg{}))
+		assert.Empty(t, exporter.GetSpans())
+		assert.NoError(t, conn.Receive(&msg{}))
+		assert.ErrorIs(t, conn.Receive(&msg{}), io.EOF)
+		assert.NoError(t, conn.CloseResponse())
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind)
+		assert.Contains(t, header.Get("traceparent"), spans[0].SpanContext.TraceID().String())
+		assert.Equal(t, []string{"SENT", "RECEIVED"}, messageEvents(spans[0]))
+		assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	})
+
+	t.Run("records errors", func(t *testing.T) {
+		interceptor, exporter := newInterceptor()
+		conn := interceptor.WrapStreamingClient(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+			return noOpClientConn{
+				spec:      spec,
+				reqHeader: http.Header{},
+				receive: func(msg any) error {
+					return connect.NewError(connect.CodeCanceled, errors.New("canceled"))
+				},
+			}
+		})(context.Background(), spec)
+
+		assert.Error(t, conn.Receive(&msg{}))
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, codes.Error, spans[0].Status.Code)
+	})
+}
+
+type noOpConn struct {
+	spec      connect.Spec
+	peer      connect.Peer
+	reqHeader http.Header
+}
+
+func (n noOpConn) Spec() connect.Spec {
+	return n.spec
+}
+
+func (n noOpConn) Peer() connect.Peer {
+	return n.peer
+}
+
+func (n noOpConn) Receive(msg any) error {
+	return nil
+}
+
+func (n noOpConn) RequestHeader() http.Header {
+	return n.reqHeader
+}
+
+func (n noOpConn) Send(msg any) error {
+	return nil
+}
+
+func (n noOpConn) ResponseHeader() http.Header {
+	return http.Header{}
+}
+
+func (n noOpConn) ResponseTrailer() http.Header {
+	return http.Header{}
+}
+
+type noOpClientConn struct {
+	receive   func(msg any) error
+	spec      connect.Spec
+	reqHeader http.Header
+}
+
+func (n noOpClientConn) Spec() connect.Spec {
+	return n.spec
+}
+
+func (n noOpClientConn) Peer() connect.Peer {
+	return connect.Peer{}
+}
+
+func (n noOpClientConn) Send(msg any) error {
+	return nil
+}
+
+func (n noOpClientConn) RequestHeader() http.Header {
+	return n.reqHeader
+}
+
+func (n noOpClientConn) CloseRequest() error {
+	return nil
+}
+
+func (n noOpClientConn) Receive(msg any) error {
+	if n.receive != nil {
+		return n.receive(msg)
+	}
+	return nil
+}
+
+func (n noOpClientConn) ResponseHeader() http.Header {
+	return http.Header{}
+}
+
+func (n noOpClientConn) ResponseTrailer() http.Header {
+	return http.Header{}
+}
+
+func (n noOpClientConn) CloseResponse() error {
+	return nil
+}
@@ -0,0 +1,81 @@
+package otel
+
+import (
+	"errors"
+	"github.com/bufbuild/connect-go"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"sync"
+)
+
+type tracingHandler struct {
+	connect.StreamingHandlerConn
+	span     trace.Span
+	received int
+	sent     int
+}
+
+func (t *tracingHandler) Receive(msg any) error {
+	err := t.StreamingHandlerConn.Receive(msg)
+	if err == nil {
+		t.received++
+		messageEvent(t.span, semconv.MessageTypeReceived, t.received)
+	}
+	return err
+}
+
+func (t *tracingHandler) Send(msg any) error {
+	err := t.StreamingHandlerConn.Send(msg)
+	if err == nil {
+		t.sent++
+		messageEvent(t.span, semconv.MessageTypeSent, t.sent)
+	}
+	return err
+}
+
+// tracingClient ends the span when the response stream ends or is closed.
+// Send and Receive may be called concurrently so they use separate counters.
+type tracingClient struct {
+	connect.StreamingClientConn
+	span     trace.Span
+	received int
+	sent     int
+	endOnce  sync.Once
+}
+
+func (t *tracingClient) Send(msg any) error {
+	err := t.StreamingClientConn.Send(msg)
+	if err == nil {
+		t.sent++
+		messageEvent(t.span, semconv.MessageTypeSent, t.sent)
+	}
+	return err
+}
+
+func (t *tracingClient) Receive(msg any) error {
+	err := t.StreamingClientConn.Receive(msg)
+	switch {
+	case err == nil:
+		t.received++
+		messageEvent(t.span, semconv.MessageTypeReceived, t.received)
+	case errors.Is(err, io.EOF):
+		t.end(nil)
+	default:
+		t.end(err)
+	}
+	return err
+}
+
+func (t *tracingClient) CloseResponse() error {
+	err := t.StreamingClientConn.CloseResponse()
+	t.end(nil)
+	return err
+}
+
+func (t *tracingClient) end(err error) {
+	t.endOnce.Do(func() {
+		setStatus(t.span, t.Spec(), t.Peer(), err)
+		t.span.End()
+	})
+}
@@ -7,36 +7,54 @@ import (
 )
 
 type interceptor struct {
-	serverStartedCounter          *prom.CounterVec
-	serverHandledCounter          *prom.CounterVec
-	serverStreamMsgReceived       *prom.CounterVec
-	serverStreamMsgSent           *prom.CounterVec
-	serverHandledHistogramEnabled bool
-	serverHandledHistogramOpts    prom.HistogramOpts
-	serverHandledHistogram        *prom.HistogramVec
+	server              *metrics
+	client              *metrics
+	exemplarFromContext func(ctx context.Context) prom.Labels
+	initializeAllCodes  bool
+	protocolLabel       bool
+	labels              []*customLabel
 }
 
 func (p *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
 	return func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
-		r := newReporter(request.Spec(), p)
+		r := newReporter(ctx, request.Spec(), request.Peer(), request.Header(), p)
 		r.monitorStart()
-		r.monitorReceive()
+		if request.Spec().IsClient {
+			r.monitorSend(request.Any())
+			response, err := next(ctx, request)
+			r.monitorDone(err)
+			if err == nil {
+				r.monitorReceive(response.Any())
+			}
+			return response, err
+		}
+		r.monitorReceive(request.Any())
 		response, err := next(ctx, request)
 		r.monitorDone(err)
 		if err == nil {
-			r.monitorSend()
+			r.monitorSend(response.Any())
 		}
 		return response, err
 	}
 }
 
 func (p *interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
-	return next
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		// The request header can be set until the first message is sent, so
+		// the label values are only computed when the stream is first used.
+		return &monitoringClient{
+			StreamingClientConn: conn,
+			newReporter: func() reporter {
+				return newReporter(ctx, spec, conn.Peer(), conn.RequestHeader(), p)
+			},
+		}
+	}
 }
 
 func (p *interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
 	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
-		r := newReporter(conn.Spec(), p)
+		r := newReporter(ctx, conn.Spec(), conn.Peer(), conn.RequestHeader(), p)
 		r.monitorStart()
 		err := next(ctx, &monitoringHandler{
 			StreamingHandlerConn: conn,
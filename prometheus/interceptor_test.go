@@ -6,7 +6,17 @@ import (
 	"github.com/bufbuild/connect-go"
 	prom "github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"io"
 	"net/http"
 	"strings"
 	"testing"
@@ -16,6 +26,19 @@ import (
 type msg struct {
 }
 
+func TestNewPrometheusInterceptor(t *testing.T) {
+	counterOpts := []CounterOption{WithConstLabels(prom.Labels{"foo": "bar"})}
+	prometheusInterceptor := NewPrometheusInterceptor(counterOpts...)
+	prometheusInterceptor.InitializeMetrics(testServiceDescriptor(t))
+
+	assert.NoError(t, testutil.CollectAndCompare(prometheusInterceptor, strings.NewReader(`
+		# HELP grpc_server_started_total Total number of RPCs started on the server.
+		# TYPE grpc_server_started_total counter
+		grpc_server_started_total{foo="bar",grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="unary"} 0
+		grpc_server_started_total{foo="bar",grpc_method="Baz",grpc_service="foo.v1.FooService",grpc_type="bidi_stream"} 0
+	`), "grpc_server_started_total"))
+}
+
 func Test_interceptor_WrapUnary(t *testing.T) {
 	prometheusInterceptor := NewPrometheusInterceptor(WithConstLabels(prom.Labels{
 		"foo": "bar",
@@ -117,15 +140,608 @@ func Test_interceptor_WrapUnary(t *testing.T) {
 
 }
 
-func Test_interceptor_WrapStreamingClient(t *testing.T) {
+func Test_interceptor_EnableHandlingTimeHistogram(t *testing.T) {
+	expectedRequest := connect.NewRequest(&msg{})
+
+	gather := func(t *testing.T, prometheusInterceptor PrometheusInterceptor) *dto.Histogram {
+		registry := prom.NewPedanticRegistry()
+		require.NoError(t, registry.Register(prometheusInterceptor))
+		families, err := registry.Gather()
+		require.NoError(t, err)
+		for _, family := range families {
+			if family.GetName() == "grpc_server_handling_seconds" {
+				require.Len(t, family.GetMetric(), 1)
+				return family.GetMetric()[0].GetHistogram()
+			}
+		}
+		require.Fail(t, "grpc_server_handling_seconds not found")
+		return nil
+	}
+
+	observe := func(prometheusInterceptor PrometheusInterceptor) {
+		_, _ = prometheusInterceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			return connect.NewResponse(&msg{}), nil
+		})(context.Background(), expectedRequest)
+	}
+
+	defer func() {
+		sinceFunc = time.Since
+	}()
+	sinceFunc = func(_ time.Time) time.Duration {
+		return 100 * time.Millisecond
+	}
+
+	t.Run("native and classic", func(t *testing.T) {
+		prometheusInterceptor := NewPrometheusInterceptor()
+		prometheusInterceptor.EnableHandlingTimeHistogram(
+			WithNativeHistogramBucketFactor(1.1),
+			WithNativeHistogramMaxBucketNumber(100),
+			WithNativeHistogramMinResetDuration(time.Hour),
+			WithNativeHistogramZeroThreshold(0.001),
+		)
+		observe(prometheusInterceptor)
+
+		histogram := gather(t, prometheusInterceptor)
+		assert.Equal(t, int32(3), histogram.GetSchema())
+		assert.Equal(t, 0.001, histogram.GetZeroThreshold())
+		assert.Equal(t, []int64{1}, histogram.GetPositiveDelta())
+		assert.Len(t, histogram.GetBucket(), len(prom.DefBuckets))
+	})
+
+	t.Run("native only", func(t *testing.T) {
+		prometheusInterceptor := NewPrometheusInterceptor()
+		prometheusInterceptor.EnableHandlingTimeHistogram(
+			WithNativeHistogramBucketFactor(1.1),
+			WithHistogramBuckets(nil),
+		)
+		observe(prometheusInterceptor)
+
+		histogram := gather(t, prometheusInterceptor)
+		assert.Equal(t, int32(3), histogram.GetSchema())
+		assert.Empty(t, histogram.GetBucket())
+	})
+}
+
+func Test_interceptor_WithExemplarFromContext(t *testing.T) {
+	type traceIDKey struct{}
+	prometheusInterceptor := NewInterceptor(WithExemplarFromContext(func(ctx context.Context) prom.Labels {
+		if traceID, ok := ctx.Value(traceIDKey{}).(string); ok {
+			return prom.Labels{"trace_id": traceID}
+		}
+		return nil
+	}))
+	prometheusInterceptor.EnableHandlingTimeHistogram()
+
+	for _, ctx := range []context.Context{
+		context.WithValue(context.Background(), traceIDKey{}, "4bf92f3577b34da6a3ce929d0e0e4736"),
+		context.Background(),
+	} {
+		_, err := prometheusInterceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			return connect.NewResponse(&msg{}), nil
+		})(ctx, connect.NewRequest(&msg{}))
+		assert.NoError(t, err)
+	}
+
+	registry := prom.NewPedanticRegistry()
+	require.NoError(t, registry.Register(prometheusInterceptor))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	var exemplars []*dto.Exemplar
+	for _, family := range families {
+		switch family.GetName() {
+		case "grpc_server_handled_total":
+			counter := family.GetMetric()[0].GetCounter()
+			assert.Equal(t, float64(2), counter.GetValue())
+			exemplars = append(exemplars, counter.GetExemplar())
+		case "grpc_server_handling_seconds":
+			for _, bucket := range family.GetMetric()[0].GetHistogram().GetBucket() {
+				if bucket.GetExemplar() != nil {
+					exemplars = append(exemplars, bucket.GetExemplar())
+				}
+			}
+		}
+	}
+	require.Len(t, exemplars, 2)
+	for _, exemplar := range exemplars {
+		require.Len(t, exemplar.GetLabel(), 1)
+		assert.Equal(t, "trace_id", exemplar.GetLabel()[0].GetName())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", exemplar.GetLabel()[0].GetValue())
+	}
+}
+
+func Test_interceptor_WithExemplarFromContext_invalid(t *testing.T) {
+	for name, labels := range map[string]prom.Labels{
+		"too long":     {"trace_id": strings.Repeat("a", prom.ExemplarMaxRunes)},
+		"invalid name": {"trace-id": "4bf92f3577b34da6a3ce929d0e0e4736"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			prometheusInterceptor := NewInterceptor(WithExemplarFromContext(func(ctx context.Context) prom.Labels {
+				return labels
+			}))
+			prometheusInterceptor.EnableHandlingTimeHistogram()
+
+			assert.NotPanics(t, func() {
+				_, err := prometheusInterceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+					return connect.NewResponse(&msg{}), nil
+				})(context.Background(), connect.NewRequest(&msg{}))
+				assert.NoError(t, err)
+			})
+
+			registry := prom.NewPedanticRegistry()
+			require.NoError(t, registry.Register(prometheusInterceptor))
+			families, err := registry.Gather()
+			require.NoError(t, err)
+			for _, family := range families {
+				if family.GetName() == "grpc_server_handled_total" {
+					counter := family.GetMetric()[0].GetCounter()
+					assert.Equal(t, float64(1), counter.GetValue())
+					assert.Nil(t, counter.GetExemplar())
+				}
+			}
+			assert.Equal(t, 1, testutil.CollectAndCount(prometheusInterceptor, "grpc_server_handling_seconds"))
+		})
+	}
+}
+
+func Test_interceptor_EnableMessageSizeHistogram(t *testing.T) {
 	prometheusInterceptor := NewPrometheusInterceptor()
-	var called bool
-	next := connect.StreamingClientFunc(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
-		called = true
+	prometheusInterceptor.EnableMessageSizeHistogram(WithHistogramBuckets([]float64{8, 16}))
+
+	_, err := prometheusInterceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		return connect.NewResponse(wrapperspb.String("a longer response")), nil
+	})(context.Background(), connect.NewRequest(wrapperspb.String("hello")))
+	assert.NoError(t, err)
+
+	err = prometheusInterceptor.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		// Messages that are not proto.Message are counted but not measured.
+		_ = conn.Receive(&msg{})
+		return conn.Send(wrapperspb.String("hello"))
+	})(context.Background(), noOpConn{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, testutil.CollectAndCompare(prometheusInterceptor, strings.NewReader(`
+		# HELP grpc_server_msg_received_bytes Histogram of the size (bytes) of the RPC stream messages received on the server.
+		# TYPE grpc_server_msg_received_bytes histogram
+		grpc_server_msg_received_bytes_bucket{grpc_method="unknown",grpc_service="unknown",grpc_type="unary",le="8"} 1
+		grpc_server_msg_received_bytes_bucket{grpc_method="unknown",grpc_service="unknown",grpc_type="unary",le="16"} 1
+		grpc_server_msg_received_bytes_bucket{grpc_method="unknown",grpc_service="unknown",grpc_type="unary",le="+Inf"} 1
+		grpc_server_msg_received_bytes_sum{grpc_method="unknown",grpc_service="unknown",grpc_type="unary"} 7
+		grpc_server_msg_received_bytes_count{grpc_method="unknown",grpc_service="unknown",grpc_type="unary"} 1
+		# HELP grpc_server_msg_sent_bytes Histogram of the size (bytes) of the gRPC stream messages sent by the server.
+		# TYPE grpc_server_msg_sent_bytes histogram
+		grpc_server_msg_sent_bytes_bucket{grpc_method="unknown",grpc_service="unknown",grpc_type="unary",le="8"} 1
+		grpc_server_msg_sent_bytes_bucket{grpc_method="unknown",grpc_service="unknown",grpc_type="unary",le="16"} 1
+		grpc_server_msg_sent_bytes_bucket{grpc_method="unknown",grpc_service="unknown",grpc_type="unary",le="+Inf"} 2
+		grpc_server_msg_sent_bytes_sum{grpc_method="unknown",grpc_service="unknown",grpc_type="unary"} 26
+		grpc_server_msg_sent_bytes_count{grpc_method="unknown",grpc_service="unknown",grpc_type="unary"} 2
+	`), "grpc_server_msg_received_bytes", "grpc_server_msg_sent_bytes", "grpc_client_msg_sent_bytes"))
+}
+
+func Test_interceptor_inflight(t *testing.T) {
+	prometheusInterceptor := NewPrometheusInterceptor()
+
+	_, err := prometheusInterceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		assert.NoError(t, testutil.CollectAndCompare(prometheusInterceptor, strings.NewReader(`
+			# HELP grpc_server_inflight Number of RPCs currently in flight on the server.
+			# TYPE grpc_server_inflight gauge
+			grpc_server_inflight{grpc_method="unknown",grpc_service="unknown",grpc_type="unary"} 1
+		`), "grpc_server_inflight"))
+		return connect.NewResponse(&msg{}), nil
+	})(context.Background(), connect.NewRequest(&msg{}))
+	assert.NoError(t, err)
+
+	assert.NoError(t, testutil.CollectAndCompare(prometheusInterceptor, strings.NewReader(`
+		# HELP grpc_server_inflight Number of RPCs currently in flight on the server.
+		# TYPE grpc_server_inflight gauge
+		grpc_server_inflight{grpc_method="unknown",grpc_service="unknown",grpc_type="unary"} 0
+	`), "grpc_server_inflight"))
+}
+
+func Test_interceptor_EnableStreamHistogram(t *testing.T) {
+	prometheusInterceptor := NewPrometheusInterceptor()
+	prometheusInterceptor.EnableStreamHistogram(WithHistogramBuckets([]float64{1, 10}))
+
+	defer func() {
+		nowFunc = time.Now
+		sinceFunc = time.Since
+	}()
+	now := time.Time{}
+	nowFunc = func() time.Time {
+		return now
+	}
+	sinceFunc = func(t time.Time) time.Duration {
+		return now.Sub(t)
+	}
+
+	conn := bidiConn{noOpConn{}}
+	err := prometheusInterceptor.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		now = now.Add(500 * time.Millisecond)
+		_ = conn.Receive(&msg{})
+		now = now.Add(5 * time.Second)
+		_ = conn.Send(&msg{})
+		now = now.Add(20 * time.Second)
+		return nil
+	})(context.Background(), conn)
+	assert.NoError(t, err)
+
+	assert.NoError(t, testutil.CollectAndCompare(prometheusInterceptor, strings.NewReader(`
+		# HELP grpc_server_stream_duration_seconds Histogram of the lifetime (seconds) of the streaming RPCs handled by the server.
+		# TYPE grpc_server_stream_duration_seconds histogram
+		grpc_server_stream_duration_seconds_bucket{grpc_method="unknown",grpc_service="unknown",grpc_type="bidi_stream",le="1"} 0
+		grpc_server_stream_duration_seconds_bucket{grpc_method="unknown",grpc_service="unknown",grpc_type="bidi_stream",le="10"} 0
+		grpc_server_stream_duration_seconds_bucket{grpc_method="unknown",grpc_service="unknown",grpc_type="bidi_stream",le="+Inf"} 1
+		grpc_server_stream_duration_seconds_sum{grpc_method="unknown",grpc_service="unknown",grpc_type="bidi_stream"} 25.5
+		grpc_server_stream_duration_seconds_count{grpc_method="unknown",grpc_service="unknown",grpc_type="bidi_stream"} 1
+		# HELP grpc_server_stream_msg_gap_seconds Histogram of the time (seconds) between two messages of a stream, or between the start of the stream and its first message, on the server.
+		# TYPE grpc_server_stream_msg_gap_seconds histogram
+		grpc_server_stream_msg_gap_seconds_bucket{grpc_method="unknown",grpc_service="unknown",grpc_type="bidi_stream",le="1"} 1
+		grpc_server_stream_msg_gap_seconds_bucket{grpc_method="unknown",grpc_service="unknown",grpc_type="bidi_stream",le="10"} 2
+		grpc_server_stream_msg_gap_seconds_bucket{grpc_method="unknown",grpc_service="unknown",grpc_type="bidi_stream",le="+Inf"} 2
+		grpc_server_stream_msg_gap_seconds_sum{grpc_method="unknown",grpc_service="unknown",grpc_type="bidi_stream"} 5.5
+		grpc_server_stream_msg_gap_seconds_count{grpc_method="unknown",grpc_service="unknown",grpc_type="bidi_stream"} 2
+	`), "grpc_server_stream_duration_seconds", "grpc_server_stream_msg_gap_seconds"))
+
+	t.Run("ignores unary calls", func(t *testing.T) {
+		prometheusInterceptor := NewPrometheusInterceptor()
+		prometheusInterceptor.EnableStreamHistogram()
+		_, err := prometheusInterceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			return connect.NewResponse(&msg{}), nil
+		})(context.Background(), connect.NewRequest(&msg{}))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, testutil.CollectAndCount(prometheusInterceptor, "grpc_server_stream_duration_seconds", "grpc_server_stream_msg_gap_seconds"))
+	})
+}
+
+func testServiceDescriptor(t *testing.T) protoreflect.ServiceDescriptor {
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("foo/v1/foo.proto"),
+		Package:    proto.String("foo.v1"),
+		Dependency: []string{"google/protobuf/empty.proto"},
+		Syntax:     proto.String("proto3"),
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("FooService"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("Bar"),
+				InputType:  proto.String(".google.protobuf.Empty"),
+				OutputType: proto.String(".google.protobuf.Empty"),
+			}, {
+				Name:            proto.String("Baz"),
+				InputType:       proto.String(".google.protobuf.Empty"),
+				OutputType:      proto.String(".google.protobuf.Empty"),
+				ClientStreaming: proto.Bool(true),
+				ServerStreaming: proto.Bool(true),
+			}},
+		}},
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	return file.Services().Get(0)
+}
+
+func Test_interceptor_InitializeMetrics(t *testing.T) {
+	service := testServiceDescriptor(t)
+
+	t.Run("initializes OK", func(t *testing.T) {
+		prometheusInterceptor := NewPrometheusInterceptor()
+		prometheusInterceptor.EnableHandlingTimeHistogram()
+		prometheusInterceptor.InitializeMetrics(service)
+
+		assert.NoError(t, testutil.CollectAndCompare(prometheusInterceptor, strings.NewReader(`
+			# HELP grpc_server_handled_total Total number of RPCs completed on the server, regardless of success or failure.
+			# TYPE grpc_server_handled_total counter
+			grpc_server_handled_total{grpc_code="OK",grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="unary"} 0
+			grpc_server_handled_total{grpc_code="OK",grpc_method="Baz",grpc_service="foo.v1.FooService",grpc_type="bidi_stream"} 0
+			# HELP grpc_server_started_total Total number of RPCs started on the server.
+			# TYPE grpc_server_started_total counter
+			grpc_server_started_total{grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="unary"} 0
+			grpc_server_started_total{grpc_method="Baz",grpc_service="foo.v1.FooService",grpc_type="bidi_stream"} 0
+		`), "grpc_server_started_total", "grpc_server_handled_total", "grpc_client_started_total"))
+		assert.Equal(t, 2, testutil.CollectAndCount(prometheusInterceptor, "grpc_server_handling_seconds"))
+		assert.Equal(t, 2, testutil.CollectAndCount(prometheusInterceptor, "grpc_server_inflight"))
+	})
+
+	t.Run("initializes all codes", func(t *testing.T) {
+		prometheusInterceptor := NewInterceptor(WithInitializeAllCodes())
+		prometheusInterceptor.InitializeMetrics(service)
+		assert.Equal(t, 2*17, testutil.CollectAndCount(prometheusInterceptor, "grpc_server_handled_total"))
+	})
+}
+
+func Test_interceptor_WithLabels(t *testing.T) {
+	tenant := headerLabel("tenant", "Tenant")
+	tenant.AllowedValues = []string{"acme"}
+	prometheusInterceptor := NewInterceptor(WithLabels(tenant))
+
+	for _, value := range []string{"acme", "evil"} {
+		request := connect.NewRequest(&msg{})
+		request.Header().Set("Tenant", value)
+		_, err := prometheusInterceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			return connect.NewResponse(&msg{}), nil
+		})(context.Background(), request)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, testutil.CollectAndCompare(prometheusInterceptor, strings.NewReader(`
+		# HELP grpc_server_handled_total Total number of RPCs completed on the server, regardless of success or failure.
+		# TYPE grpc_server_handled_total counter
+		grpc_server_handled_total{grpc_code="OK",grpc_method="unknown",grpc_service="unknown",grpc_type="unary",tenant="acme"} 1
+		grpc_server_handled_total{grpc_code="OK",grpc_method="unknown",grpc_service="unknown",grpc_type="unary",tenant="other"} 1
+		# HELP grpc_server_started_total Total number of RPCs started on the server.
+		# TYPE grpc_server_started_total counter
+		grpc_server_started_total{grpc_method="unknown",grpc_service="unknown",grpc_type="unary",tenant="acme"} 1
+		grpc_server_started_total{grpc_method="unknown",grpc_service="unknown",grpc_type="unary",tenant="other"} 1
+	`), "grpc_server_started_total", "grpc_server_handled_total"))
+
+	t.Run("initializes allowed values", func(t *testing.T) {
+		prometheusInterceptor := NewInterceptor(WithLabels(tenant))
+		prometheusInterceptor.InitializeMetrics(testServiceDescriptor(t))
+		assert.Equal(t, 2, testutil.CollectAndCount(prometheusInterceptor, "grpc_server_handled_total"))
+	})
+}
+
+func Test_interceptor_WithNamingScheme(t *testing.T) {
+	prometheusInterceptor := NewInterceptor(
+		WithNamespace("acme"),
+		WithSubsystem("api"),
+		WithNamingScheme(ConnectNaming),
+	)
+	prometheusInterceptor.EnableHandlingTimeHistogram()
+	_, err := prometheusInterceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		return connect.NewResponse(&msg{}), nil
+	})(context.Background(), connect.NewRequest(&msg{}))
+	assert.NoError(t, err)
+
+	assert.NoError(t, testutil.CollectAndCompare(prometheusInterceptor, strings.NewReader(`
+		# HELP acme_api_connect_rpc_server_handled_total Total number of RPCs completed on the server, regardless of success or failure.
+		# TYPE acme_api_connect_rpc_server_handled_total counter
+		acme_api_connect_rpc_server_handled_total{grpc_code="OK",grpc_method="unknown",grpc_service="unknown",grpc_type="unary"} 1
+	`), "acme_api_connect_rpc_server_handled_total", "grpc_server_handled_total"))
+	assert.Equal(t, 1, testutil.CollectAndCount(prometheusInterceptor, "acme_api_connect_rpc_server_handling_seconds"))
+}
+
+func Test_interceptor_WithProtocolLabel(t *testing.T) {
+	prometheusInterceptor := NewInterceptor(WithProtocolLabel())
+	err := prometheusInterceptor.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
 		return nil
+	})(context.Background(), grpcConn{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, testutil.CollectAndCompare(prometheusInterceptor, strings.NewReader(`
+		# HELP grpc_server_started_total Total number of RPCs started on the server.
+		# TYPE grpc_server_started_total counter
+		grpc_server_started_total{grpc_method="unknown",grpc_service="unknown",grpc_type="unary",protocol="grpc"} 1
+	`), "grpc_server_started_total"))
+
+	t.Run("initializes protocols", func(t *testing.T) {
+		prometheusInterceptor := NewInterceptor(WithProtocolLabel())
+		prometheusInterceptor.InitializeMetrics(testServiceDescriptor(t))
+		assert.Equal(t, 2*3, testutil.CollectAndCount(prometheusInterceptor, "grpc_server_handled_total"))
 	})
-	prometheusInterceptor.WrapStreamingClient(next)(nil, connect.Spec{})
-	assert.True(t, called)
+}
+
+func Test_interceptor_EnableErrorCounter(t *testing.T) {
+	prometheusInterceptor := NewPrometheusInterceptor()
+	prometheusInterceptor.EnableErrorCounter()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, ctx := range []context.Context{context.Background(), canceled} {
+		_, err := prometheusInterceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			return nil, connect.NewError(connect.CodeCanceled, errors.New("canceled"))
+		})(ctx, connect.NewRequest(&msg{}))
+		assert.Error(t, err)
+	}
+	_, err := prometheusInterceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		return connect.NewResponse(&msg{}), nil
+	})(context.Background(), connect.NewRequest(&msg{}))
+	assert.NoError(t, err)
+
+	assert.NoError(t, testutil.CollectAndCompare(prometheusInterceptor, strings.NewReader(`
+		# HELP grpc_server_errors_total Total number of RPCs failed on the server by code, protocol, error source and error detail type.
+		# TYPE grpc_server_errors_total counter
+		grpc_server_errors_total{grpc_code="Canceled",grpc_error_detail_type="",grpc_error_source="connect",grpc_method="unknown",grpc_service="unknown",grpc_type="unary",protocol=""} 1
+		grpc_server_errors_total{grpc_code="Canceled",grpc_error_detail_type="",grpc_error_source="context",grpc_method="unknown",grpc_service="unknown",grpc_type="unary",protocol=""} 1
+	`), "grpc_server_errors_total"))
+
+	t.Run("uses the protocol label", func(t *testing.T) {
+		prometheusInterceptor := NewInterceptor(WithProtocolLabel())
+		prometheusInterceptor.EnableErrorCounter()
+		err := prometheusInterceptor.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+			return io.ErrUnexpectedEOF
+		})(context.Background(), grpcConn{})
+		assert.Error(t, err)
+
+		assert.NoError(t, testutil.CollectAndCompare(prometheusInterceptor, strings.NewReader(`
+			# HELP grpc_server_errors_total Total number of RPCs failed on the server by code, protocol, error source and error detail type.
+			# TYPE grpc_server_errors_total counter
+			grpc_server_errors_total{grpc_code="Unknown",grpc_error_detail_type="",grpc_error_source="transport",grpc_method="unknown",grpc_service="unknown",grpc_type="unary",protocol="grpc"} 1
+		`), "grpc_server_errors_total"))
+	})
+}
+
+func Test_interceptor_WrapUnary_client(t *testing.T) {
+	prometheusInterceptor := NewPrometheusInterceptor()
+
+	expectedRequest := &clientRequest{
+		Request: connect.NewRequest(&msg{}),
+		spec:    connect.Spec{Procedure: "/foo.v1.FooService/Bar", IsClient: true},
+	}
+	expectedResponse := connect.NewResponse(&msg{})
+
+	t.Run("collect metrics", func(t *testing.T) {
+		response, err := prometheusInterceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			assert.Same(t, expectedRequest, request)
+			return expectedResponse, nil
+		})(context.Background(), expectedRequest)
+		assert.Same(t, expectedResponse, response)
+		assert.NoError(t, err)
+
+		_, err = prometheusInterceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			return nil, connect.NewError(connect.CodeUnavailable, errors.New("unavailable"))
+		})(context.Background(), expectedRequest)
+		assert.Error(t, err)
+
+		assert.NoError(t, testutil.CollectAndCompare(prometheusInterceptor, strings.NewReader(`
+			# HELP grpc_client_handled_total Total number of RPCs completed by the client, regardless of success or failure.
+			# TYPE grpc_client_handled_total counter
+			grpc_client_handled_total{grpc_code="OK",grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="unary"} 1
+			grpc_client_handled_total{grpc_code="Unavailable",grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="unary"} 1
+			# HELP grpc_client_msg_received_total Total number of RPC stream messages received by the client.
+			# TYPE grpc_client_msg_received_total counter
+			grpc_client_msg_received_total{grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="unary"} 1
+			# HELP grpc_client_msg_sent_total Total number of gRPC stream messages sent by the client.
+			# TYPE grpc_client_msg_sent_total counter
+			grpc_client_msg_sent_total{grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="unary"} 2
+			# HELP grpc_client_started_total Total number of RPCs started on the client.
+			# TYPE grpc_client_started_total counter
+			grpc_client_started_total{grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="unary"} 2
+		`),
+			"grpc_client_started_total",
+			"grpc_client_handled_total",
+			"grpc_client_msg_received_total",
+			"grpc_client_msg_sent_total",
+			"grpc_server_started_total",
+			"grpc_server_handled_total",
+		))
+	})
+
+	t.Run("supports histograms", func(t *testing.T) {
+		prometheusInterceptor := NewPrometheusInterceptor()
+		prometheusInterceptor.EnableClientHandlingTimeHistogram(WithHistogramBuckets([]float64{1}))
+
+		defer func() {
+			sinceFunc = time.Since
+		}()
+		sinceFunc = func(_ time.Time) time.Duration {
+			return 500 * time.Millisecond
+		}
+
+		_, err := prometheusInterceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			return expectedResponse, nil
+		})(context.Background(), expectedRequest)
+		assert.NoError(t, err)
+
+		assert.NoError(t, testutil.CollectAndCompare(prometheusInterceptor, strings.NewReader(`
+			# HELP grpc_client_handling_seconds Histogram of response latency (seconds) of the gRPC until it is finished by the application.
+			# TYPE grpc_client_handling_seconds histogram
+			grpc_client_handling_seconds_bucket{grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="unary",le="1"} 1
+			grpc_client_handling_seconds_bucket{grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="unary",le="+Inf"} 1
+			grpc_client_handling_seconds_sum{grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="unary"} 0.5
+			grpc_client_handling_seconds_count{grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="unary"} 1
+		`), "grpc_client_handling_seconds", "grpc_server_handling_seconds"))
+	})
+}
+
+func Test_interceptor_WrapStreamingClient(t *testing.T) {
+	spec := connect.Spec{
+		Procedure:  "/foo.v1.FooService/Bar",
+		StreamType: connect.StreamTypeBidi,
+		IsClient:   true,
+	}
+
+	t.Run("calls next", func(t *testing.T) {
+		prometheusInterceptor := NewPrometheusInterceptor()
+		var called bool
+		next := connect.StreamingClientFunc(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+			called = true
+			return nil
+		})
+		prometheusInterceptor.WrapStreamingClient(next)(nil, connect.Spec{})
+		assert.True(t, called)
+	})
+
+	t.Run("collect metrics", func(t *testing.T) {
+		prometheusInterceptor := NewPrometheusInterceptor()
+		received := 0
+		conn := prometheusInterceptor.WrapStreamingClient(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+			return noOpClientConn{
+				spec: spec,
+				receive: func(msg any) error {
+					received++
+					if received > 2 {
+						return io.EOF
+					}
+					return nil
+				},
+			}
+		})(context.Background(), spec)
+
+		assert.NoError(t, conn.Send(&msg{}))
+		assert.NoError(t, conn.CloseRequest())
+		assert.NoError(t, conn.Receive(&msg{}))
+		assert.NoError(t, conn.Receive(&msg{}))
+		assert.ErrorIs(t, conn.Receive(&msg{}), io.EOF)
+		assert.NoError(t, conn.CloseResponse())
+
+		assert.NoError(t, testutil.CollectAndCompare(prometheusInterceptor, strings.NewReader(`
+			# HELP grpc_client_handled_total Total number of RPCs completed by the client, regardless of success or failure.
+			# TYPE grpc_client_handled_total counter
+			grpc_client_handled_total{grpc_code="OK",grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="bidi_stream"} 1
+			# HELP grpc_client_msg_received_total Total number of RPC stream messages received by the client.
+			# TYPE grpc_client_msg_received_total counter
+			grpc_client_msg_received_total{grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="bidi_stream"} 2
+			# HELP grpc_client_msg_sent_total Total number of gRPC stream messages sent by the client.
+			# TYPE grpc_client_msg_sent_total counter
+			grpc_client_msg_sent_total{grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="bidi_stream"} 1
+			# HELP grpc_client_started_total Total number of RPCs started on the client.
+			# TYPE grpc_client_started_total counter
+			grpc_client_started_total{grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="bidi_stream"} 1
+		`),
+			"grpc_client_started_total",
+			"grpc_client_handled_total",
+			"grpc_client_msg_received_total",
+			"grpc_client_msg_sent_total",
+		))
+	})
+
+	t.Run("reports errors", func(t *testing.T) {
+		prometheusInterceptor := NewPrometheusInterceptor()
+		expectedError := connect.NewError(connect.CodeInternal, errors.New("internal"))
+		conn := prometheusInterceptor.WrapStreamingClient(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+			return noOpClientConn{
+				spec: spec,
+				receive: func(msg any) error {
+					return expectedError
+				},
+			}
+		})(context.Background(), spec)
+
+		assert.Same(t, expectedError, conn.Receive(&msg{}))
+		assert.NoError(t, conn.CloseResponse())
+
+		assert.NoError(t, testutil.CollectAndCompare(prometheusInterceptor, strings.NewReader(`
+			# HELP grpc_client_handled_total Total number of RPCs completed by the client, regardless of success or failure.
+			# TYPE grpc_client_handled_total counter
+			grpc_client_handled_total{grpc_code="Internal",grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="bidi_stream"} 1
+		`), "grpc_client_handled_total"))
+	})
+}
+
+func Test_interceptor_WrapStreamingClient_WithLabels(t *testing.T) {
+	prometheusInterceptor := NewInterceptor(WithLabels(headerLabel("tenant", "Tenant")))
+	conn := prometheusInterceptor.WrapStreamingClient(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		return noOpClientConn{
+			spec:   spec,
+			header: http.Header{},
+			receive: func(msg any) error {
+				return io.EOF
+			},
+		}
+	})(context.Background(), connect.Spec{Procedure: "/foo.v1.FooService/Bar", StreamType: connect.StreamTypeBidi, IsClient: true})
+
+	conn.RequestHeader().Set("Tenant", "acme")
+	assert.NoError(t, conn.Send(&msg{}))
+	assert.NoError(t, conn.CloseRequest())
+	assert.ErrorIs(t, conn.Receive(&msg{}), io.EOF)
+
+	assert.NoError(t, testutil.CollectAndCompare(prometheusInterceptor, strings.NewReader(`
+		# HELP grpc_client_handled_total Total number of RPCs completed by the client, regardless of success or failure.
+		# TYPE grpc_client_handled_total counter
+		grpc_client_handled_total{grpc_code="OK",grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="bidi_stream",tenant="acme"} 1
+		# HELP grpc_client_started_total Total number of RPCs started on the client.
+		# TYPE grpc_client_started_total counter
+		grpc_client_started_total{grpc_method="Bar",grpc_service="foo.v1.FooService",grpc_type="bidi_stream",tenant="acme"} 1
+	`), "grpc_client_started_total", "grpc_client_handled_total"))
 }
 
 func Test_interceptor_WrapStreamingHandler(t *testing.T) {
@@ -254,6 +870,79 @@ func Test_interceptor_WrapStreamingHandler(t *testing.T) {
 
 }
 
+type clientRequest struct {
+	*connect.Request[msg]
+	spec connect.Spec
+}
+
+func (c *clientRequest) Spec() connect.Spec {
+	return c.spec
+}
+
+type noOpClientConn struct {
+	receive func(msg any) error
+	spec    connect.Spec
+	header  http.Header
+}
+
+func (n noOpClientConn) Spec() connect.Spec {
+	return n.spec
+}
+
+func (n noOpClientConn) Peer() connect.Peer {
+	return connect.Peer{}
+}
+
+func (n noOpClientConn) Send(msg any) error {
+	return nil
+}
+
+func (n noOpClientConn) RequestHeader() http.Header {
+	if n.header != nil {
+		return n.header
+	}
+	return http.Header{}
+}
+
+func (n noOpClientConn) CloseRequest() error {
+	return nil
+}
+
+func (n noOpClientConn) Receive(msg any) error {
+	if n.receive != nil {
+		return n.receive(msg)
+	}
+	return nil
+}
+
+func (n noOpClientConn) ResponseHeader() http.Header {
+	return http.Header{}
+}
+
+func (n noOpClientConn) ResponseTrailer() http.Header {
+	return http.Header{}
+}
+
+func (n noOpClientConn) CloseResponse() error {
+	return nil
+}
+
+type bidiConn struct {
+	noOpConn
+}
+
+func (b bidiConn) Spec() connect.Spec {
+	return connect.Spec{StreamType: connect.StreamTypeBidi}
+}
+
+type grpcConn struct {
+	noOpConn
+}
+
+func (g grpcConn) Peer() connect.Peer {
+	return connect.Peer{Protocol: connect.ProtocolGRPC}
+}
+
 type noOpConn struct {
 	receive func(msg any) error
 	send    func(msg any) error
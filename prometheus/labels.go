@@ -0,0 +1,120 @@
+package prometheus
+
+import (
+	"context"
+	"github.com/bufbuild/connect-go"
+	"net/http"
+	"sync"
+)
+
+// OverflowValue is the value reported for custom labels whose value is not allowed.
+const OverflowValue = "other"
+
+// A Label is a custom label added to all the metrics. Its value is computed
+// per call, e.g. from a request header or from a token stored in the context by
+// an authentication interceptor registered before the PrometheusInterceptor.
+//
+// Every value of a label creates new series. AllowedValues and MaxValues guard
+// against unbounded values coming from the callers: values that are rejected
+// are reported as OverflowValue.
+type Label struct {
+	Name    string
+	Extract func(ctx context.Context, spec connect.Spec, header http.Header) string
+	// AllowedValues restricts the label to the given values if not empty.
+	AllowedValues []string
+	// MaxValues limits the number of distinct values of the label if greater
+	// than zero. The first values seen are kept.
+	MaxValues int
+}
+
+// WithLabels adds custom labels to the metrics.
+func WithLabels(labels ...Label) Option {
+	return optionFunc(func(o *interceptorOptions) {
+		for _, label := range labels {
+			o.labels = append(o.labels, newCustomLabel(label))
+		}
+	})
+}
+
+type customLabel struct {
+	Label
+	allowed map[string]struct{}
+	seenMu  sync.Mutex
+	seen    map[string]struct{}
+}
+
+func newCustomLabel(label Label) *customLabel {
+	l := &customLabel{Label: label}
+	if len(label.AllowedValues) > 0 {
+		l.allowed = make(map[string]struct{}, len(label.AllowedValues))
+		for _, value := range label.AllowedValues {
+			l.allowed[value] = struct{}{}
+		}
+	}
+	if label.MaxValues > 0 {
+		l.seen = make(map[string]struct{}, label.MaxValues)
+	}
+	return l
+}
+
+func (l *customLabel) value(ctx context.Context, spec connect.Spec, header http.Header) string {
+	value := l.Extract(ctx, spec, header)
+	if l.allowed != nil {
+		if _, ok := l.allowed[value]; !ok {
+			return OverflowValue
+		}
+	}
+	if l.seen != nil {
+		l.seenMu.Lock()
+		defer l.seenMu.Unlock()
+		if _, ok := l.seen[value]; !ok {
+			if len(l.seen) >= l.MaxValues {
+				return OverflowValue
+			}
+			l.seen[value] = struct{}{}
+		}
+	}
+	return value
+}
+
+// initialValues returns the values InitializeMetrics creates series for.
+func (l *customLabel) initialValues() []string {
+	if len(l.AllowedValues) > 0 {
+		return l.AllowedValues
+	}
+	return []string{""}
+}
+
+func labelNames(labels []*customLabel) []string {
+	names := make([]string, len(labels))
+	for i, label := range labels {
+		names[i] = label.Name
+	}
+	return names
+}
+
+// labelCombinations returns every combination of the initial values of the labels.
+func labelCombinations(labels []*customLabel) [][]string {
+	combinations := [][]string{{}}
+	for _, label := range labels {
+		var next [][]string
+		for _, combination := range combinations {
+			for _, value := range label.initialValues() {
+				next = append(next, append(append([]string{}, combination...), value))
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// protocolCombinations prefixes the combinations with each protocol.
+func protocolCombinations(combinations [][]string) [][]string {
+	var next [][]string
+	for _, protocol := range []string{connect.ProtocolConnect, connect.ProtocolGRPC, connect.ProtocolGRPCWeb} {
+		for _, combination := range combinations {
+			next = append(next, append([]string{protocol}, combination...))
+		}
+	}
+	return next
+}
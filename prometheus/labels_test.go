@@ -0,0 +1,62 @@
+package prometheus
+
+import (
+	"context"
+	"github.com/bufbuild/connect-go"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func headerLabel(name, header string) Label {
+	return Label{
+		Name: name,
+		Extract: func(ctx context.Context, spec connect.Spec, h http.Header) string {
+			return h.Get(header)
+		},
+	}
+}
+
+func Test_customLabel_value(t *testing.T) {
+	value := func(label *customLabel, v string) string {
+		return label.value(context.Background(), connect.Spec{}, http.Header{"Tenant": {v}})
+	}
+
+	t.Run("unguarded", func(t *testing.T) {
+		label := newCustomLabel(headerLabel("tenant", "Tenant"))
+		assert.Equal(t, "foo", value(label, "foo"))
+		assert.Equal(t, "bar", value(label, "bar"))
+	})
+
+	t.Run("allowed values", func(t *testing.T) {
+		l := headerLabel("tenant", "Tenant")
+		l.AllowedValues = []string{"foo", "bar"}
+		label := newCustomLabel(l)
+		assert.Equal(t, "foo", value(label, "foo"))
+		assert.Equal(t, "bar", value(label, "bar"))
+		assert.Equal(t, OverflowValue, value(label, "baz"))
+		assert.Equal(t, OverflowValue, value(label, ""))
+	})
+
+	t.Run("max values", func(t *testing.T) {
+		l := headerLabel("tenant", "Tenant")
+		l.MaxValues = 2
+		label := newCustomLabel(l)
+		assert.Equal(t, "foo", value(label, "foo"))
+		assert.Equal(t, "bar", value(label, "bar"))
+		assert.Equal(t, OverflowValue, value(label, "baz"))
+		assert.Equal(t, "foo", value(label, "foo"))
+	})
+}
+
+func Test_labelCombinations(t *testing.T) {
+	tenant := headerLabel("tenant", "Tenant")
+	tenant.AllowedValues = []string{"foo", "bar"}
+	client := headerLabel("client", "Client")
+
+	assert.Equal(t, [][]string{{}}, labelCombinations(nil))
+	assert.Equal(t, [][]string{{"foo", ""}, {"bar", ""}}, labelCombinations([]*customLabel{
+		newCustomLabel(tenant),
+		newCustomLabel(client),
+	}))
+}
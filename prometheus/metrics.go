@@ -0,0 +1,245 @@
+package prometheus
+
+import (
+	"github.com/bufbuild/connect-go"
+	"github.com/hadrienk/connect-go-interceptors/common"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// metrics holds the collectors for one side (server or client) of an RPC.
+type metrics struct {
+	extraLabels             []string
+	startedCounter          *prom.CounterVec
+	handledCounter          *prom.CounterVec
+	streamMsgReceived       *prom.CounterVec
+	streamMsgSent           *prom.CounterVec
+	handledHistogramEnabled bool
+	handledHistogramOpts    prom.HistogramOpts
+	handledHistogram        *prom.HistogramVec
+	msgSizeHistogramEnabled bool
+	msgReceivedSizeOpts     prom.HistogramOpts
+	msgSentSizeOpts         prom.HistogramOpts
+	msgReceivedSize         *prom.HistogramVec
+	msgSentSize             *prom.HistogramVec
+	inflightGauge           *prom.GaugeVec
+	streamHistogramEnabled  bool
+	streamDurationOpts      prom.HistogramOpts
+	streamMsgGapOpts        prom.HistogramOpts
+	streamDuration          *prom.HistogramVec
+	streamMsgGap            *prom.HistogramVec
+	errorCounterEnabled     bool
+	errorCounterOpts        prom.CounterOpts
+	errorLabels             []string
+	errorCounter            *prom.CounterVec
+}
+
+func methodLabels(extraLabels []string) []string {
+	return append([]string{"grpc_type", "grpc_service", "grpc_method"}, extraLabels...)
+}
+
+func handledLabels(extraLabels []string) []string {
+	return append([]string{"grpc_type", "grpc_service", "grpc_method", "grpc_code"}, extraLabels...)
+}
+
+// errorLabels are the handled labels followed by the protocol, unless it is
+// already an extra label, the source of the error and the type URL of its
+// first detail.
+func errorLabels(o *interceptorOptions) []string {
+	labels := handledLabels(o.extraLabels())
+	if !o.protocolLabel {
+		labels = append(labels, "protocol")
+	}
+	return append(labels, "grpc_error_source", "grpc_error_detail_type")
+}
+
+// defMessageSizeBuckets go from 64 bytes to 1MiB.
+var defMessageSizeBuckets = prom.ExponentialBuckets(64, 4, 8)
+
+// defStreamBuckets go from 100ms to about 1 hour since streams are usually long-lived.
+var defStreamBuckets = prom.ExponentialBuckets(0.1, 4, 9)
+
+func newServerMetrics(o *interceptorOptions) *metrics {
+	extraLabels := o.extraLabels()
+	return &metrics{
+		extraLabels: extraLabels,
+		startedCounter: prom.NewCounterVec(
+			o.newCounterOpts("server", "started_total", "Total number of RPCs started on the server."),
+			methodLabels(extraLabels)),
+		handledCounter: prom.NewCounterVec(
+			o.newCounterOpts("server", "handled_total", "Total number of RPCs completed on the server, regardless of success or failure."),
+			handledLabels(extraLabels)),
+		streamMsgReceived: prom.NewCounterVec(
+			o.newCounterOpts("server", "msg_received_total", "Total number of RPC stream messages received on the server."),
+			methodLabels(extraLabels)),
+		streamMsgSent: prom.NewCounterVec(
+			o.newCounterOpts("server", "msg_sent_total", "Total number of gRPC stream messages sent by the server."),
+			methodLabels(extraLabels)),
+		handledHistogramEnabled: false,
+		handledHistogramOpts:    o.newHistogramOpts("server", "handling_seconds", "Histogram of response latency (seconds) of gRPC that had been application-level handled by the server.", prom.DefBuckets),
+		handledHistogram:        nil,
+		msgReceivedSizeOpts:     o.newHistogramOpts("server", "msg_received_bytes", "Histogram of the size (bytes) of the RPC stream messages received on the server.", defMessageSizeBuckets),
+		msgSentSizeOpts:         o.newHistogramOpts("server", "msg_sent_bytes", "Histogram of the size (bytes) of the gRPC stream messages sent by the server.", defMessageSizeBuckets),
+		inflightGauge: prom.NewGaugeVec(
+			o.newGaugeOpts("server", "inflight", "Number of RPCs currently in flight on the server."),
+			methodLabels(extraLabels)),
+		streamDurationOpts: o.newHistogramOpts("server", "stream_duration_seconds", "Histogram of the lifetime (seconds) of the streaming RPCs handled by the server.", defStreamBuckets),
+		streamMsgGapOpts:   o.newHistogramOpts("server", "stream_msg_gap_seconds", "Histogram of the time (seconds) between two messages of a stream, or between the start of the stream and its first message, on the server.", defStreamBuckets),
+		errorCounterOpts:   o.newCounterOpts("server", "errors_total", "Total number of RPCs failed on the server by code, protocol, error source and error detail type."),
+		errorLabels:        errorLabels(o),
+	}
+}
+
+func newClientMetrics(o *interceptorOptions) *metrics {
+	extraLabels := o.extraLabels()
+	return &metrics{
+		extraLabels: extraLabels,
+		startedCounter: prom.NewCounterVec(
+			o.newCounterOpts("client", "started_total", "Total number of RPCs started on the client."),
+			methodLabels(extraLabels)),
+		handledCounter: prom.NewCounterVec(
+			o.newCounterOpts("client", "handled_total", "Total number of RPCs completed by the client, regardless of success or failure."),
+			handledLabels(extraLabels)),
+		streamMsgReceived: prom.NewCounterVec(
+			o.newCounterOpts("client", "msg_received_total", "Total number of RPC stream messages received by the client."),
+			methodLabels(extraLabels)),
+		streamMsgSent: prom.NewCounterVec(
+			o.newCounterOpts("client", "msg_sent_total", "Total number of gRPC stream messages sent by the client."),
+			methodLabels(extraLabels)),
+		handledHistogramEnabled: false,
+		handledHistogramOpts:    o.newHistogramOpts("client", "handling_seconds", "Histogram of response latency (seconds) of the gRPC until it is finished by the application.", prom.DefBuckets),
+		handledHistogram:        nil,
+		msgReceivedSizeOpts:     o.newHistogramOpts("client", "msg_received_bytes", "Histogram of the size (bytes) of the RPC stream messages received by the client.", defMessageSizeBuckets),
+		msgSentSizeOpts:         o.newHistogramOpts("client", "msg_sent_bytes", "Histogram of the size (bytes) of the gRPC stream messages sent by the client.", defMessageSizeBuckets),
+		inflightGauge: prom.NewGaugeVec(
+			o.newGaugeOpts("client", "inflight", "Number of RPCs currently in flight on the client."),
+			methodLabels(extraLabels)),
+		streamDurationOpts: o.newHistogramOpts("client", "stream_duration_seconds", "Histogram of the lifetime (seconds) of the streaming RPCs started by the client.", defStreamBuckets),
+		streamMsgGapOpts:   o.newHistogramOpts("client", "stream_msg_gap_seconds", "Histogram of the time (seconds) between two messages of a stream, or between the start of the stream and its first message, on the client.", defStreamBuckets),
+		errorCounterOpts:   o.newCounterOpts("client", "errors_total", "Total number of RPCs failed on the client by code, protocol, error source and error detail type."),
+		errorLabels:        errorLabels(o),
+	}
+}
+
+func (m *metrics) enableHandlingTimeHistogram(opts ...HistogramOption) {
+	for _, o := range opts {
+		o(&m.handledHistogramOpts)
+	}
+	if !m.handledHistogramEnabled {
+		m.handledHistogram = prom.NewHistogramVec(
+			m.handledHistogramOpts,
+			methodLabels(m.extraLabels),
+		)
+	}
+	m.handledHistogramEnabled = true
+}
+
+func (m *metrics) enableMessageSizeHistogram(opts ...HistogramOption) {
+	for _, o := range opts {
+		o(&m.msgReceivedSizeOpts)
+		o(&m.msgSentSizeOpts)
+	}
+	if !m.msgSizeHistogramEnabled {
+		m.msgReceivedSize = prom.NewHistogramVec(
+			m.msgReceivedSizeOpts,
+			methodLabels(m.extraLabels),
+		)
+		m.msgSentSize = prom.NewHistogramVec(
+			m.msgSentSizeOpts,
+			methodLabels(m.extraLabels),
+		)
+	}
+	m.msgSizeHistogramEnabled = true
+}
+
+func (m *metrics) enableStreamHistogram(opts ...HistogramOption) {
+	for _, o := range opts {
+		o(&m.streamDurationOpts)
+		o(&m.streamMsgGapOpts)
+	}
+	if !m.streamHistogramEnabled {
+		m.streamDuration = prom.NewHistogramVec(
+			m.streamDurationOpts,
+			methodLabels(m.extraLabels),
+		)
+		m.streamMsgGap = prom.NewHistogramVec(
+			m.streamMsgGapOpts,
+			methodLabels(m.extraLabels),
+		)
+	}
+	m.streamHistogramEnabled = true
+}
+
+func (m *metrics) enableErrorCounter() {
+	if !m.errorCounterEnabled {
+		m.errorCounter = prom.NewCounterVec(m.errorCounterOpts, m.errorLabels)
+	}
+	m.errorCounterEnabled = true
+}
+
+// initialize creates the series of a method with a zero value. The grpc_code
+// label of the handled counter takes each of the given codes.
+func (m *metrics) initialize(streamType, serviceName, methodName string, extraValues []string, codes []string) {
+	values := append([]string{streamType, serviceName, methodName}, extraValues...)
+	m.startedCounter.WithLabelValues(values...)
+	m.streamMsgReceived.WithLabelValues(values...)
+	m.streamMsgSent.WithLabelValues(values...)
+	m.inflightGauge.WithLabelValues(values...)
+	for _, code := range codes {
+		m.handledCounter.WithLabelValues(append([]string{streamType, serviceName, methodName, code}, extraValues...)...)
+	}
+	if m.handledHistogramEnabled {
+		m.handledHistogram.WithLabelValues(values...)
+	}
+	if m.msgSizeHistogramEnabled {
+		m.msgReceivedSize.WithLabelValues(values...)
+		m.msgSentSize.WithLabelValues(values...)
+	}
+	if m.streamHistogramEnabled && streamType != common.StreamTypeString(connect.StreamTypeUnary) {
+		m.streamDuration.WithLabelValues(values...)
+		m.streamMsgGap.WithLabelValues(values...)
+	}
+}
+
+func (m *metrics) describe(ch chan<- *prom.Desc) {
+	m.startedCounter.Describe(ch)
+	m.handledCounter.Describe(ch)
+	m.streamMsgReceived.Describe(ch)
+	m.streamMsgSent.Describe(ch)
+	m.inflightGauge.Describe(ch)
+	if m.handledHistogramEnabled {
+		m.handledHistogram.Describe(ch)
+	}
+	if m.msgSizeHistogramEnabled {
+		m.msgReceivedSize.Describe(ch)
+		m.msgSentSize.Describe(ch)
+	}
+	if m.streamHistogramEnabled {
+		m.streamDuration.Describe(ch)
+		m.streamMsgGap.Describe(ch)
+	}
+	if m.errorCounterEnabled {
+		m.errorCounter.Describe(ch)
+	}
+}
+
+func (m *metrics) collect(ch chan<- prom.Metric) {
+	m.startedCounter.Collect(ch)
+	m.handledCounter.Collect(ch)
+	m.streamMsgReceived.Collect(ch)
+	m.streamMsgSent.Collect(ch)
+	m.inflightGauge.Collect(ch)
+	if m.handledHistogramEnabled {
+		m.handledHistogram.Collect(ch)
+	}
+	if m.msgSizeHistogramEnabled {
+		m.msgReceivedSize.Collect(ch)
+		m.msgSentSize.Collect(ch)
+	}
+	if m.streamHistogramEnabled {
+		m.streamDuration.Collect(ch)
+		m.streamMsgGap.Collect(ch)
+	}
+	if m.errorCounterEnabled {
+		m.errorCounter.Collect(ch)
+	}
+}
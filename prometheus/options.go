@@ -1,10 +1,138 @@
 package prometheus
 
-import prom "github.com/prometheus/client_golang/prometheus"
+import (
+	"context"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// An Option configures the PrometheusInterceptor. CounterOption values are
+// Options as well.
+type Option interface {
+	applyInterceptor(*interceptorOptions)
+}
+
+type interceptorOptions struct {
+	counterOpts         counterOptions
+	namespace           string
+	subsystem           string
+	namingScheme        NamingScheme
+	protocolLabel       bool
+	exemplarFromContext func(ctx context.Context) prom.Labels
+	initializeAllCodes  bool
+	labels              []*customLabel
+}
+
+type optionFunc func(*interceptorOptions)
+
+func (f optionFunc) applyInterceptor(o *interceptorOptions) {
+	f(o)
+}
+
+// name returns the name of a metric of the given side (server or client).
+func (o *interceptorOptions) name(side, name string) string {
+	return string(o.namingScheme) + "_" + side + "_" + name
+}
+
+func (o *interceptorOptions) newCounterOpts(side, name, help string) prom.CounterOpts {
+	return o.counterOpts.apply(prom.CounterOpts{
+		Namespace: o.namespace,
+		Subsystem: o.subsystem,
+		Name:      o.name(side, name),
+		Help:      help,
+	})
+}
+
+func (o *interceptorOptions) newGaugeOpts(side, name, help string) prom.GaugeOpts {
+	return prom.GaugeOpts(o.newCounterOpts(side, name, help))
+}
+
+func (o *interceptorOptions) newHistogramOpts(side, name, help string, buckets []float64) prom.HistogramOpts {
+	return prom.HistogramOpts{
+		Namespace: o.namespace,
+		Subsystem: o.subsystem,
+		Name:      o.name(side, name),
+		Help:      help,
+		Buckets:   buckets,
+	}
+}
+
+// extraLabels returns the names of the labels added after the method labels.
+func (o *interceptorOptions) extraLabels() []string {
+	var names []string
+	if o.protocolLabel {
+		names = append(names, "protocol")
+	}
+	return append(names, labelNames(o.labels)...)
+}
+
+// A NamingScheme is the prefix of the names of the metrics.
+type NamingScheme string
+
+const (
+	// GRPCNaming names the metrics like grpc-prometheus, e.g. grpc_server_handled_total.
+	GRPCNaming NamingScheme = "grpc"
+	// ConnectNaming names the metrics connect_rpc_*, e.g. connect_rpc_server_handled_total.
+	ConnectNaming NamingScheme = "connect_rpc"
+)
+
+// WithNamespace prefixes the names of all the metrics with the namespace.
+func WithNamespace(namespace string) Option {
+	return optionFunc(func(o *interceptorOptions) {
+		o.namespace = namespace
+	})
+}
+
+// WithSubsystem prefixes the names of all the metrics with the subsystem,
+// after the namespace.
+func WithSubsystem(subsystem string) Option {
+	return optionFunc(func(o *interceptorOptions) {
+		o.subsystem = subsystem
+	})
+}
+
+// WithNamingScheme sets the naming scheme of the metrics. Defaults to GRPCNaming.
+// The labels keep their names.
+func WithNamingScheme(scheme NamingScheme) Option {
+	return optionFunc(func(o *interceptorOptions) {
+		o.namingScheme = scheme
+	})
+}
+
+// WithProtocolLabel adds a protocol label to all the metrics with the protocol
+// of the call as reported by connect.Peer: connect, grpc or grpcweb.
+func WithProtocolLabel() Option {
+	return optionFunc(func(o *interceptorOptions) {
+		o.protocolLabel = true
+	})
+}
+
+// WithExemplarFromContext attaches the labels returned by the function as an
+// exemplar to the handled counters and handling time histograms, e.g. to link
+// them to the trace of the call. No exemplar is attached when the function
+// returns nil or labels that are invalid or longer than
+// prometheus.ExemplarMaxRunes.
+func WithExemplarFromContext(exemplarFromContext func(ctx context.Context) prom.Labels) Option {
+	return optionFunc(func(o *interceptorOptions) {
+		o.exemplarFromContext = exemplarFromContext
+	})
+}
+
+// WithInitializeAllCodes makes InitializeMetrics create the handled series of
+// every code instead of only OK.
+func WithInitializeAllCodes() Option {
+	return optionFunc(func(o *interceptorOptions) {
+		o.initializeAllCodes = true
+	})
+}
 
 // A CounterOption lets you add options to Counter metrics using With* funcs.
 type CounterOption func(*prom.CounterOpts)
 
+func (co CounterOption) applyInterceptor(o *interceptorOptions) {
+	o.counterOpts = append(o.counterOpts, co)
+}
+
 type counterOptions []CounterOption
 
 func (co counterOptions) apply(o prom.CounterOpts) prom.CounterOpts {
@@ -37,3 +165,41 @@ func WithHistogramConstLabels(labels prom.Labels) HistogramOption {
 		o.ConstLabels = labels
 	}
 }
+
+// WithNativeHistogramBucketFactor enables native (sparse) histograms. The factor
+// is the maximum ratio between the upper bounds of two consecutive buckets and
+// must be greater than one.
+//
+// The classic buckets are still exposed so both representations can be scraped
+// during a migration. Use WithHistogramBuckets(nil) to only expose the native
+// histogram.
+func WithNativeHistogramBucketFactor(factor float64) HistogramOption {
+	return func(o *prom.HistogramOpts) {
+		o.NativeHistogramBucketFactor = factor
+	}
+}
+
+// WithNativeHistogramMaxBucketNumber limits the number of buckets of the native
+// histograms. Once the limit is reached the resolution is reduced, or the
+// histogram is reset if it has not been reset for WithNativeHistogramMinResetDuration.
+func WithNativeHistogramMaxBucketNumber(max uint32) HistogramOption {
+	return func(o *prom.HistogramOpts) {
+		o.NativeHistogramMaxBucketNumber = max
+	}
+}
+
+// WithNativeHistogramMinResetDuration sets the minimum duration between two
+// resets of the native histograms when WithNativeHistogramMaxBucketNumber is reached.
+func WithNativeHistogramMinResetDuration(duration time.Duration) HistogramOption {
+	return func(o *prom.HistogramOpts) {
+		o.NativeHistogramMinResetDuration = duration
+	}
+}
+
+// WithNativeHistogramZeroThreshold sets the width of the zero bucket of the
+// native histograms. Observations closer to zero end up in the zero bucket.
+func WithNativeHistogramZeroThreshold(threshold float64) HistogramOption {
+	return func(o *prom.HistogramOpts) {
+		o.NativeHistogramZeroThreshold = threshold
+	}
+}
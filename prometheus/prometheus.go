@@ -2,47 +2,56 @@ package prometheus
 
 import (
 	"github.com/bufbuild/connect-go"
+	"github.com/hadrienk/connect-go-interceptors/common"
 	prom "github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 type PrometheusInterceptor interface {
 	prom.Collector
 	connect.Interceptor
 	EnableHandlingTimeHistogram(opts ...HistogramOption)
+	EnableClientHandlingTimeHistogram(opts ...HistogramOption)
+	EnableMessageSizeHistogram(opts ...HistogramOption)
+	EnableClientMessageSizeHistogram(opts ...HistogramOption)
+	EnableStreamHistogram(opts ...HistogramOption)
+	EnableClientStreamHistogram(opts ...HistogramOption)
+	EnableErrorCounter()
+	EnableClientErrorCounter()
+	InitializeMetrics(services ...protoreflect.ServiceDescriptor)
 }
 
 // NewPrometheusInterceptor returns a PrometheusInterceptor object. It implements both
-// the prometheus.Collector and connect.Interceptor interface.
+// the prometheus.Collector and connect.Interceptor interface. It only takes
+// CounterOption values, use NewInterceptor for the other options.
 func NewPrometheusInterceptor(counterOpts ...CounterOption) PrometheusInterceptor {
-	opts := counterOptions(counterOpts)
+	opts := make([]Option, len(counterOpts))
+	for i, co := range counterOpts {
+		opts[i] = co
+	}
+	return NewInterceptor(opts...)
+}
+
+// NewInterceptor returns a PrometheusInterceptor configured with the options.
+//
+// The interceptor can be used both on handlers and on clients. The side is
+// chosen per call from connect.Spec.IsClient; handlers record grpc_server_*
+// metrics and clients record grpc_client_* metrics. The names can be changed
+// with WithNamespace, WithSubsystem and WithNamingScheme.
+func NewInterceptor(opts ...Option) PrometheusInterceptor {
+	o := interceptorOptions{
+		namingScheme: GRPCNaming,
+	}
+	for _, opt := range opts {
+		opt.applyInterceptor(&o)
+	}
 	return &interceptor{
-		serverStartedCounter: prom.NewCounterVec(
-			opts.apply(prom.CounterOpts{
-				Name: "grpc_server_started_total",
-				Help: "Total number of RPCs started on the server.",
-			}), []string{"grpc_type", "grpc_service", "grpc_method"}),
-		serverHandledCounter: prom.NewCounterVec(
-			opts.apply(prom.CounterOpts{
-				Name: "grpc_server_handled_total",
-				Help: "Total number of RPCs completed on the server, regardless of success or failure.",
-			}), []string{"grpc_type", "grpc_service", "grpc_method", "grpc_code"}),
-		serverStreamMsgReceived: prom.NewCounterVec(
-			opts.apply(prom.CounterOpts{
-				Name: "grpc_server_msg_received_total",
-				Help: "Total number of RPC stream messages received on the server.",
-			}), []string{"grpc_type", "grpc_service", "grpc_method"}),
-		serverStreamMsgSent: prom.NewCounterVec(
-			opts.apply(prom.CounterOpts{
-				Name: "grpc_server_msg_sent_total",
-				Help: "Total number of gRPC stream messages sent by the server.",
-			}), []string{"grpc_type", "grpc_service", "grpc_method"}),
-		serverHandledHistogramEnabled: false,
-		serverHandledHistogramOpts: prom.HistogramOpts{
-			Name:    "grpc_server_handling_seconds",
-			Help:    "Histogram of response latency (seconds) of gRPC that had been application-level handled by the server.",
-			Buckets: prom.DefBuckets,
-		},
-		serverHandledHistogram: nil,
+		server:              newServerMetrics(&o),
+		client:              newClientMetrics(&o),
+		exemplarFromContext: o.exemplarFromContext,
+		initializeAllCodes:  o.initializeAllCodes,
+		protocolLabel:       o.protocolLabel,
+		labels:              o.labels,
 	}
 }
 
@@ -51,40 +60,103 @@ func NewPrometheusInterceptor(counterOpts ...CounterOption) PrometheusIntercepto
 // expensive on Prometheus servers. It takes options to configure histogram
 // options such as the defined buckets.
 func (p *interceptor) EnableHandlingTimeHistogram(opts ...HistogramOption) {
-	for _, o := range opts {
-		o(&p.serverHandledHistogramOpts)
+	p.server.enableHandlingTimeHistogram(opts...)
+}
+
+// EnableClientHandlingTimeHistogram enables the client side equivalent of
+// EnableHandlingTimeHistogram. The latency is measured until the response
+// has been fully received by the application.
+func (p *interceptor) EnableClientHandlingTimeHistogram(opts ...HistogramOption) {
+	p.client.enableHandlingTimeHistogram(opts...)
+}
+
+// EnableMessageSizeHistogram enables the histograms of the size of the messages
+// received and sent by the server. The size is the marshaled size of the proto
+// messages, other messages are not observed.
+func (p *interceptor) EnableMessageSizeHistogram(opts ...HistogramOption) {
+	p.server.enableMessageSizeHistogram(opts...)
+}
+
+// EnableClientMessageSizeHistogram enables the client side equivalent of
+// EnableMessageSizeHistogram.
+func (p *interceptor) EnableClientMessageSizeHistogram(opts ...HistogramOption) {
+	p.client.enableMessageSizeHistogram(opts...)
+}
+
+// EnableStreamHistogram enables the histograms of the lifetime of the client,
+// server and bidi streams handled by the server and of the time between their
+// messages. They help to spot stuck long-lived streams.
+func (p *interceptor) EnableStreamHistogram(opts ...HistogramOption) {
+	p.server.enableStreamHistogram(opts...)
+}
+
+// EnableClientStreamHistogram enables the client side equivalent of
+// EnableStreamHistogram.
+func (p *interceptor) EnableClientStreamHistogram(opts ...HistogramOption) {
+	p.client.enableStreamHistogram(opts...)
+}
+
+// EnableErrorCounter enables the grpc_server_errors_total counter. It breaks the
+// failed RPCs down by code and protocol like the handled counter, by the source
+// of the error (ErrorSourceContext, ErrorSourceConnect or ErrorSourceTransport)
+// and by the type URL of the first detail of the error. It tells a client that
+// gave up apart from a handler that returned Canceled.
+func (p *interceptor) EnableErrorCounter() {
+	p.server.enableErrorCounter()
+}
+
+// EnableClientErrorCounter enables the client side equivalent of
+// EnableErrorCounter. Only the errors received from the server are reported
+// with ErrorSourceConnect.
+func (p *interceptor) EnableClientErrorCounter() {
+	p.client.enableErrorCounter()
+}
+
+// InitializeMetrics creates the server series of every method of the services
+// with a zero value so that they are exported before the methods are first
+// called. The histograms must be enabled before calling InitializeMetrics to be
+// initialized as well. Only the OK code is initialized unless
+// WithInitializeAllCodes is used. Custom labels are initialized with each of
+// their AllowedValues, or with an empty value, and the protocol label with
+// each protocol.
+func (p *interceptor) InitializeMetrics(services ...protoreflect.ServiceDescriptor) {
+	codes := []string{common.CodeString(nil)}
+	if p.initializeAllCodes {
+		codes = allCodes()
 	}
-	if !p.serverHandledHistogramEnabled {
-		p.serverHandledHistogram = prom.NewHistogramVec(
-			p.serverHandledHistogramOpts,
-			[]string{"grpc_type", "grpc_service", "grpc_method"},
-		)
+	combinations := labelCombinations(p.labels)
+	if p.protocolLabel {
+		combinations = protocolCombinations(combinations)
+	}
+	for _, service := range services {
+		methods := service.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			method := methods.Get(i)
+			for _, extraValues := range combinations {
+				p.server.initialize(
+					common.StreamTypeString(methodStreamType(method)),
+					string(service.FullName()),
+					string(method.Name()),
+					extraValues,
+					codes,
+				)
+			}
+		}
 	}
-	p.serverHandledHistogramEnabled = true
 }
 
 // Describe sends the super-set of all possible descriptors of metrics
 // collected by this Collector to the provided channel and returns once
 // the last descriptor has been sent.
 func (p *interceptor) Describe(ch chan<- *prom.Desc) {
-	p.serverStartedCounter.Describe(ch)
-	p.serverHandledCounter.Describe(ch)
-	p.serverStreamMsgReceived.Describe(ch)
-	p.serverStreamMsgSent.Describe(ch)
-	if p.serverHandledHistogramEnabled {
-		p.serverHandledHistogram.Describe(ch)
-	}
+	p.server.describe(ch)
+	p.client.describe(ch)
 }
 
 // Collect is called by the Prometheus registry when collecting
 // metrics. The implementation sends each collected metric via the
 // provided channel and returns once the last metric has been sent.
 func (p *interceptor) Collect(ch chan<- prom.Metric) {
-	p.serverStartedCounter.Collect(ch)
-	p.serverHandledCounter.Collect(ch)
-	p.serverStreamMsgReceived.Collect(ch)
-	p.serverStreamMsgSent.Collect(ch)
-	if p.serverHandledHistogramEnabled {
-		p.serverHandledHistogram.Collect(ch)
-	}
+	p.server.collect(ch)
+	p.client.collect(ch)
 }
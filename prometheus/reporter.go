@@ -1,126 +1,237 @@
 package prometheus
 
 import (
+	"context"
+	"errors"
 	"github.com/bufbuild/connect-go"
-	"strconv"
+	"github.com/hadrienk/connect-go-interceptors/common"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 type grpcReporter struct {
-	metrics *interceptor
-	start   time.Time
-	spec    connect.Spec
+	metrics             *metrics
+	start               time.Time
+	lastMessageMu       sync.Mutex
+	lastMessage         time.Time
+	ctx                 context.Context
+	spec                connect.Spec
+	labelValues         []string
+	protocol            string
+	protocolLabel       bool
+	exemplarFromContext func(ctx context.Context) prom.Labels
 }
 
 type reporter interface {
 	monitorStart()
-	monitorSend()
-	monitorReceive()
+	monitorSend(msg any)
+	monitorReceive(msg any)
 	monitorDone(err error)
 }
 
 var sinceFunc = time.Since
 var nowFunc = time.Now
 
-func newReporter(spec connect.Spec, metrics *interceptor) reporter {
+func newReporter(ctx context.Context, spec connect.Spec, peer connect.Peer, header http.Header, interceptor *interceptor) reporter {
+	metrics := interceptor.server
+	if spec.IsClient {
+		metrics = interceptor.client
+	}
+	serviceName, methodName := common.SplitProcedure(spec.Procedure)
+	labelValues := []string{common.StreamTypeString(spec.StreamType), serviceName, methodName}
+	if interceptor.protocolLabel {
+		labelValues = append(labelValues, peer.Protocol)
+	}
+	for _, label := range interceptor.labels {
+		labelValues = append(labelValues, label.value(ctx, spec, header))
+	}
 	return &grpcReporter{
-		metrics: metrics,
-		spec:    spec,
+		metrics:             metrics,
+		ctx:                 ctx,
+		spec:                spec,
+		labelValues:         labelValues,
+		protocol:            peer.Protocol,
+		protocolLabel:       interceptor.protocolLabel,
+		exemplarFromContext: interceptor.exemplarFromContext,
 	}
 }
 
-func (p *grpcReporter) labelValues() (streamType, serviceName, methodName string) {
-	serviceName, methodName = splitMethodName(p.spec.Procedure)
-	streamType = streamTypeString(p.spec.StreamType)
-	return
+// handledLabelValues returns the label values of the handled counter, which
+// has the grpc_code label after the method labels.
+func (p *grpcReporter) handledLabelValues(err error) []string {
+	values := append([]string{}, p.labelValues[:3]...)
+	values = append(values, common.CodeString(err))
+	return append(values, p.labelValues[3:]...)
+}
+
+// errorLabelValues returns the label values of the error counter.
+func (p *grpcReporter) errorLabelValues(err error) []string {
+	values := p.handledLabelValues(err)
+	if !p.protocolLabel {
+		values = append(values, p.protocol)
+	}
+	return append(values, errorSource(p.ctx, p.spec, err), errorDetailType(err))
 }
 
 func (p *grpcReporter) monitorStart() {
-	p.metrics.serverStartedCounter.WithLabelValues(p.labelValues()).Inc()
-	if p.metrics.serverHandledHistogramEnabled {
+	p.metrics.startedCounter.WithLabelValues(p.labelValues...).Inc()
+	p.metrics.inflightGauge.WithLabelValues(p.labelValues...).Inc()
+	if p.metrics.handledHistogramEnabled || p.streamHistogramEnabled() {
 		p.start = nowFunc()
+		p.lastMessage = p.start
 	}
 }
 
-func (p *grpcReporter) monitorSend() {
-	p.metrics.serverStreamMsgSent.WithLabelValues(p.labelValues()).Inc()
+func (p *grpcReporter) streamHistogramEnabled() bool {
+	return p.metrics.streamHistogramEnabled && p.spec.StreamType != connect.StreamTypeUnary
 }
 
-func (p *grpcReporter) monitorReceive() {
-	p.metrics.serverStreamMsgReceived.WithLabelValues(p.labelValues()).Inc()
+// monitorMessage observes the time elapsed since the previous message of the
+// stream, or since its start.
+func (p *grpcReporter) monitorMessage() {
+	if !p.streamHistogramEnabled() {
+		return
+	}
+	p.lastMessageMu.Lock()
+	gap := sinceFunc(p.lastMessage)
+	p.lastMessage = nowFunc()
+	p.lastMessageMu.Unlock()
+	p.metrics.streamMsgGap.WithLabelValues(p.labelValues...).Observe(gap.Seconds())
+}
+
+func (p *grpcReporter) monitorSend(msg any) {
+	p.metrics.streamMsgSent.WithLabelValues(p.labelValues...).Inc()
+	p.monitorMessage()
+	if p.metrics.msgSizeHistogramEnabled {
+		if protoMsg, ok := msg.(proto.Message); ok {
+			p.metrics.msgSentSize.WithLabelValues(p.labelValues...).Observe(float64(proto.Size(protoMsg)))
+		}
+	}
+}
+
+func (p *grpcReporter) monitorReceive(msg any) {
+	p.metrics.streamMsgReceived.WithLabelValues(p.labelValues...).Inc()
+	p.monitorMessage()
+	if p.metrics.msgSizeHistogramEnabled {
+		if protoMsg, ok := msg.(proto.Message); ok {
+			p.metrics.msgReceivedSize.WithLabelValues(p.labelValues...).Observe(float64(proto.Size(protoMsg)))
+		}
+	}
 }
 
 func (p *grpcReporter) monitorDone(err error) {
-	streamType, serviceName, methodName := p.labelValues()
-	if p.metrics.serverHandledHistogramEnabled {
-		p.metrics.serverHandledHistogram.WithLabelValues(streamType, serviceName, methodName).Observe(sinceFunc(p.start).Seconds())
-	}
-	p.metrics.serverHandledCounter.WithLabelValues(streamType, serviceName, methodName, errorString(err)).Inc()
-}
-
-func errorString(err error) string {
-	if err == nil {
-		return "OK"
-	}
-	code := connect.CodeOf(err)
-	switch code {
-	case connect.CodeCanceled:
-		return "Canceled"
-	case connect.CodeUnknown:
-		return "Unknown"
-	case connect.CodeInvalidArgument:
-		return "InvalidArgument"
-	case connect.CodeDeadlineExceeded:
-		return "DeadlineExceeded"
-	case connect.CodeNotFound:
-		return "NotFound"
-	case connect.CodeAlreadyExists:
-		return "AlreadyExists"
-	case connect.CodePermissionDenied:
-		return "PermissionDenied"
-	case connect.CodeResourceExhausted:
-		return "ResourceExhausted"
-	case connect.CodeFailedPrecondition:
-		return "FailedPrecondition"
-	case connect.CodeAborted:
-		return "Aborted"
-	case connect.CodeOutOfRange:
-		return "OutOfRange"
-	case connect.CodeUnimplemented:
-		return "Unimplemented"
-	case connect.CodeInternal:
-		return "Internal"
-	case connect.CodeUnavailable:
-		return "Unavailable"
-	case connect.CodeDataLoss:
-		return "DataLoss"
-	case connect.CodeUnauthenticated:
-		return "Unauthenticated"
+	p.metrics.inflightGauge.WithLabelValues(p.labelValues...).Dec()
+	if p.streamHistogramEnabled() {
+		p.metrics.streamDuration.WithLabelValues(p.labelValues...).Observe(sinceFunc(p.start).Seconds())
+	}
+	exemplar := p.exemplar()
+	if p.metrics.handledHistogramEnabled {
+		observer := p.metrics.handledHistogram.WithLabelValues(p.labelValues...)
+		if exemplar != nil {
+			observer.(prom.ExemplarObserver).ObserveWithExemplar(sinceFunc(p.start).Seconds(), exemplar)
+		} else {
+			observer.Observe(sinceFunc(p.start).Seconds())
+		}
+	}
+	counter := p.metrics.handledCounter.WithLabelValues(p.handledLabelValues(err)...)
+	if exemplar != nil {
+		counter.(prom.ExemplarAdder).AddWithExemplar(1, exemplar)
+	} else {
+		counter.Inc()
+	}
+	if err != nil && p.metrics.errorCounterEnabled {
+		p.metrics.errorCounter.WithLabelValues(p.errorLabelValues(err)...).Inc()
+	}
+}
+
+func (p *grpcReporter) exemplar() prom.Labels {
+	if p.exemplarFromContext == nil {
+		return nil
+	}
+	exemplar := p.exemplarFromContext(p.ctx)
+	if !validExemplar(exemplar) {
+		return nil
+	}
+	return exemplar
+}
+
+// validExemplar checks the labels with the rules of client_golang, which
+// panics when observing an invalid exemplar.
+func validExemplar(labels prom.Labels) bool {
+	runes := 0
+	for name, value := range labels {
+		if !model.LabelName(name).IsValid() || strings.HasPrefix(name, model.ReservedLabelPrefix) || !utf8.ValidString(value) {
+			return false
+		}
+		runes += utf8.RuneCountInString(name) + utf8.RuneCountInString(value)
+	}
+	return runes <= prom.ExemplarMaxRunes
+}
+
+// The values of the grpc_error_source label.
+const (
+	// ErrorSourceContext is reported when the context of the call was canceled
+	// or its deadline exceeded, e.g. because the client gave up.
+	ErrorSourceContext = "context"
+	// ErrorSourceConnect is reported for the *connect.Error returned by the
+	// handler, or received from the server on the client.
+	ErrorSourceConnect = "connect"
+	// ErrorSourceTransport is reported for the other errors, e.g. a network
+	// error or a plain error returned by the handler.
+	ErrorSourceTransport = "transport"
+)
+
+func errorSource(ctx context.Context, spec connect.Spec, err error) string {
+	if ctx.Err() != nil {
+		return ErrorSourceContext
+	}
+	var connectErr *connect.Error
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ErrorSourceContext
+	case spec.IsClient && connect.IsWireError(err):
+		return ErrorSourceConnect
+	case !spec.IsClient && errors.As(err, &connectErr):
+		return ErrorSourceConnect
 	default:
-		return "Code(" + strconv.FormatInt(int64(code), 10) + ")"
+		return ErrorSourceTransport
+	}
+}
+
+// errorDetailType returns the type URL of the first detail of the error, if any.
+func errorDetailType(err error) string {
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) || len(connectErr.Details()) == 0 {
+		return ""
 	}
+	return "type.googleapis.com/" + connectErr.Details()[0].Type()
 }
 
-func splitMethodName(fullMethodName string) (string, string) {
-	fullMethodName = strings.TrimPrefix(fullMethodName, "/") // remove leading slash
-	if i := strings.Index(fullMethodName, "/"); i >= 0 {
-		return fullMethodName[:i], fullMethodName[i+1:]
+// allCodes returns the grpc_code label values of every connect.Code, including OK.
+func allCodes() []string {
+	codes := []string{common.CodeString(nil)}
+	for code := connect.CodeCanceled; code <= connect.CodeUnauthenticated; code++ {
+		codes = append(codes, common.CodeString(connect.NewError(code, nil)))
 	}
-	return "unknown", "unknown"
+	return codes
 }
 
-func streamTypeString(streamType connect.StreamType) string {
+func methodStreamType(method protoreflect.MethodDescriptor) connect.StreamType {
 	switch {
-	case streamType == connect.StreamTypeUnary:
-		return "unary"
-	case streamType == connect.StreamTypeClient:
-		return "client_stream"
-	case streamType == connect.StreamTypeServer:
-		return "server_stream"
-	case streamType == connect.StreamTypeBidi:
-		return "bidi_stream"
+	case method.IsStreamingClient() && method.IsStreamingServer():
+		return connect.StreamTypeBidi
+	case method.IsStreamingClient():
+		return connect.StreamTypeClient
+	case method.IsStreamingServer():
+		return connect.StreamTypeServer
 	default:
-		return "unknown"
+		return connect.StreamTypeUnary
 	}
 }
@@ -1,7 +1,10 @@
 package prometheus
 
 import (
+	"errors"
 	"github.com/bufbuild/connect-go"
+	"io"
+	"sync"
 )
 
 type monitoringHandler struct {
@@ -12,7 +15,7 @@ type monitoringHandler struct {
 func (m monitoringHandler) Receive(msg any) error {
 	err := m.StreamingHandlerConn.Receive(msg)
 	if err == nil {
-		m.reporter.monitorReceive()
+		m.reporter.monitorReceive(msg)
 	}
 	return err
 }
@@ -20,7 +23,70 @@ func (m monitoringHandler) Receive(msg any) error {
 func (m monitoringHandler) Send(msg any) error {
 	err := m.StreamingHandlerConn.Send(msg)
 	if err == nil {
-		m.reporter.monitorSend()
+		m.reporter.monitorSend(msg)
 	}
 	return err
 }
+
+type monitoringClient struct {
+	connect.StreamingClientConn
+	newReporter func() reporter
+	startOnce   sync.Once
+	reporter    reporter
+	doneOnce    sync.Once
+}
+
+// start reports the start of the call the first time the stream is used,
+// once the application had the chance to set the request header.
+func (m *monitoringClient) start() reporter {
+	m.startOnce.Do(func() {
+		m.reporter = m.newReporter()
+		m.reporter.monitorStart()
+	})
+	return m.reporter
+}
+
+func (m *monitoringClient) Send(msg any) error {
+	r := m.start()
+	err := m.StreamingClientConn.Send(msg)
+	if err == nil {
+		r.monitorSend(msg)
+	}
+	return err
+}
+
+func (m *monitoringClient) CloseRequest() error {
+	m.start()
+	return m.StreamingClientConn.CloseRequest()
+}
+
+// Receive reports the end of the call once the server closes the stream. Like
+// grpc-go, io.EOF is considered a successful completion.
+func (m *monitoringClient) Receive(msg any) error {
+	r := m.start()
+	err := m.StreamingClientConn.Receive(msg)
+	switch {
+	case err == nil:
+		r.monitorReceive(msg)
+	case errors.Is(err, io.EOF):
+		m.done(nil)
+	default:
+		m.done(err)
+	}
+	return err
+}
+
+// CloseResponse reports the call as done if the application stops reading
+// before the end of the stream.
+func (m *monitoringClient) CloseResponse() error {
+	m.start()
+	err := m.StreamingClientConn.CloseResponse()
+	m.done(nil)
+	return err
+}
+
+func (m *monitoringClient) done(err error) {
+	m.doneOnce.Do(func() {
+		m.reporter.monitorDone(err)
+	})
+}
@@ -0,0 +1,88 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+)
+
+// pendingSignature is added to the context of the calls by the signing
+// interceptor for the transport to sign their body.
+type pendingSignature struct {
+	secret    []byte
+	procedure string
+	timestamp string
+	nonce     string
+}
+
+type pendingSignatureKey struct{}
+
+// NewTransport returns a transport that signs the requests of the calls
+// signed by the interceptor returned by NewSigningInterceptor, over the bytes
+// of the body as sent. The other requests are sent as is by the base
+// transport, or by http.DefaultTransport if it is nil.
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	pending, ok := r.Context().Value(pendingSignatureKey{}).(*pendingSignature)
+	if !ok {
+		return t.base.RoundTrip(r)
+	}
+	body, err := readBody(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r = r.Clone(r.Context())
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	r.ContentLength = int64(len(body))
+	r.Header.Set(HeaderSignature, sign(pending.secret, pending.procedure, pending.timestamp, pending.nonce, digest(body)))
+	return t.base.RoundTrip(r)
+}
+
+type bodyDigestKey struct{}
+
+// NewHandler returns a handler that reads the body of the requests and adds
+// its digest to their context before calling the handler, so that the
+// interceptor returned by NewVerifyingInterceptor verifies the signature over
+// the bytes of the body as received. It wraps the handlers of connect.
+func NewHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := readBody(r.Body)
+		if err != nil {
+			http.Error(w, "could not read body", http.StatusBadRequest)
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), bodyDigestKey{}, digest(body)))
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func readBody(body io.ReadCloser) ([]byte, error) {
+	if body == nil || body == http.NoBody {
+		return nil, nil
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// digest returns the hex encoded SHA-256 digest of the body.
+func digest(body []byte) string {
+	hash := sha256.Sum256(body)
+	return hex.EncodeToString(hash[:])
+}
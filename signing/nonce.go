@@ -0,0 +1,28 @@
+package signing
+
+import (
+	"context"
+	"github.com/hadrienk/connect-go-interceptors/common"
+	"time"
+)
+
+// A NonceStore remembers the nonces of the signed requests to reject the
+// replayed requests.
+type NonceStore interface {
+	// CheckAndStore stores the nonce until the expiry and returns true, or
+	// returns false if the nonce is already stored.
+	CheckAndStore(ctx context.Context, nonce string, expiry time.Time) (bool, error)
+}
+
+// NewMemoryNonceStore returns a NonceStore keeping the nonces in memory.
+func NewMemoryNonceStore() NonceStore {
+	return memoryNonceStore{seen: common.NewExpiringSet()}
+}
+
+type memoryNonceStore struct {
+	seen *common.ExpiringSet
+}
+
+func (m memoryNonceStore) CheckAndStore(_ context.Context, nonce string, expiry time.Time) (bool, error) {
+	return m.seen.Add(nonce, nowFunc(), expiry), nil
+}
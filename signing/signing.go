@@ -0,0 +1,218 @@
+package signing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/bufbuild/connect-go"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The headers of the signed requests.
+const (
+	HeaderKeyID     = "X-Signature-Key-Id"
+	HeaderTimestamp = "X-Signature-Timestamp"
+	HeaderNonce     = "X-Signature-Nonce"
+	HeaderSignature = "X-Signature"
+)
+
+var nowFunc = time.Now
+
+// NewSigningInterceptor returns a client interceptor that signs the unary
+// requests with the secret. The signature is the HMAC-SHA256 of the method,
+// the procedure, the timestamp, a nonce and the SHA-256 digest of the request
+// body, see NewVerifyingInterceptor. The key ID tells the server which secret
+// to verify the signature with so that the secrets can be rotated.
+//
+// The body is only known once it is encoded, so the signature is computed by
+// the transport returned by NewTransport, which the HTTP client of the calls
+// must use.
+func NewSigningInterceptor(keyID string, secret []byte) connect.Interceptor {
+	return &signingInterceptor{keyID: keyID, secret: secret}
+}
+
+type signingInterceptor struct {
+	keyID  string
+	secret []byte
+}
+
+func (s *signingInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		if !request.Spec().IsClient {
+			return next(ctx, request)
+		}
+		nonce, err := newNonce()
+		if err != nil {
+			return nil, connect.NewError(connect.CodeInternal, err)
+		}
+		timestamp := strconv.FormatInt(nowFunc().Unix(), 10)
+		request.Header().Set(HeaderKeyID, s.keyID)
+		request.Header().Set(HeaderTimestamp, timestamp)
+		request.Header().Set(HeaderNonce, nonce)
+		ctx = context.WithValue(ctx, pendingSignatureKey{}, &pendingSignature{
+			secret:    s.secret,
+			procedure: request.Spec().Procedure,
+			timestamp: timestamp,
+			nonce:     nonce,
+		})
+		return next(ctx, request)
+	}
+}
+
+func (s *signingInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (s *signingInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+func newNonce() (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("could not generate nonce: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(nonce), nil
+}
+
+// sign returns the base64 encoded HMAC-SHA256 of the request with the digest
+// of its body. Unary requests are always sent with POST.
+func sign(secret []byte, procedure, timestamp, nonce, bodyDigest string) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s", http.MethodPost, procedure, timestamp, nonce, bodyDigest)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Secrets looks up the secrets by key ID.
+type Secrets interface {
+	// Secret returns the secret of the key ID, or nil if there is none.
+	Secret(ctx context.Context, keyID string) ([]byte, error)
+}
+
+// StaticSecrets are Secrets held in a map. Rotating a secret means adding the
+// new key ID, moving the clients to it and removing the old key ID.
+type StaticSecrets map[string][]byte
+
+func (s StaticSecrets) Secret(_ context.Context, keyID string) ([]byte, error) {
+	return s[keyID], nil
+}
+
+// An Option configures the verifying interceptor.
+type Option func(*verifyingInterceptor)
+
+// WithSkew accepts the requests signed at most skew before or after the
+// current time instead of 5 minutes.
+func WithSkew(skew time.Duration) Option {
+	return func(v *verifyingInterceptor) {
+		v.skew = skew
+	}
+}
+
+// WithNonceStore remembers the nonces in the store instead of in memory. A
+// shared store is needed to reject the requests replayed against another
+// instance of the server.
+func WithNonceStore(store NonceStore) Option {
+	return func(v *verifyingInterceptor) {
+		v.nonceStore = store
+	}
+}
+
+type verifyingInterceptor struct {
+	secrets    Secrets
+	skew       time.Duration
+	nonceStore NonceStore
+}
+
+type keyIDKey struct{}
+
+// KeyIDFromContext returns the key ID of the call verified by the interceptor
+// returned by NewVerifyingInterceptor.
+func KeyIDFromContext(ctx context.Context) (string, bool) {
+	keyID, ok := ctx.Value(keyIDKey{}).(string)
+	return keyID, ok
+}
+
+// NewVerifyingInterceptor returns a server interceptor that verifies the
+// signature of the unary requests signed by the interceptor returned by
+// NewSigningInterceptor. The handlers must be wrapped with NewHandler for the
+// interceptor to get the digest of the request body. The requests signed outside the clock skew window or
+// whose nonce was already seen are rejected. Streaming calls cannot be signed
+// and are rejected.
+func NewVerifyingInterceptor(secrets Secrets, options ...Option) connect.Interceptor {
+	v := &verifyingInterceptor{secrets: secrets, skew: 5 * time.Minute}
+	for _, option := range options {
+		option(v)
+	}
+	if v.nonceStore == nil {
+		v.nonceStore = NewMemoryNonceStore()
+	}
+	return v
+}
+
+func (v *verifyingInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		if request.Spec().IsClient {
+			return next(ctx, request)
+		}
+		keyID, err := v.verify(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return next(context.WithValue(ctx, keyIDKey{}, keyID), request)
+	}
+}
+
+func (v *verifyingInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (v *verifyingInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		return connect.NewError(connect.CodeUnauthenticated, errors.New("streaming calls cannot be signed"))
+	}
+}
+
+func (v *verifyingInterceptor) verify(ctx context.Context, request connect.AnyRequest) (string, error) {
+	header := request.Header()
+	keyID, timestamp, nonce, signature := header.Get(HeaderKeyID), header.Get(HeaderTimestamp), header.Get(HeaderNonce), header.Get(HeaderSignature)
+	if keyID == "" || timestamp == "" || nonce == "" || signature == "" {
+		return "", connect.NewError(connect.CodeUnauthenticated, errors.New("missing signature"))
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("malformed timestamp: %w", err))
+	}
+	signedAt := time.Unix(seconds, 0)
+	if now := nowFunc(); signedAt.Before(now.Add(-v.skew)) || signedAt.After(now.Add(v.skew)) {
+		return "", connect.NewError(connect.CodeUnauthenticated, errors.New("signature outside of the clock skew window"))
+	}
+	secret, err := v.secrets.Secret(ctx, keyID)
+	if err != nil {
+		return "", connect.NewError(connect.CodeUnavailable, fmt.Errorf("could not look up secret: %w", err))
+	}
+	if secret == nil {
+		return "", connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("unknown key ID %s", keyID))
+	}
+	bodyDigest, ok := ctx.Value(bodyDigestKey{}).(string)
+	if !ok {
+		return "", connect.NewError(connect.CodeInternal, errors.New("missing body digest, the handler is not wrapped with signing.NewHandler"))
+	}
+	expected := sign(secret, request.Spec().Procedure, timestamp, nonce, bodyDigest)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", connect.NewError(connect.CodeUnauthenticated, errors.New("invalid signature"))
+	}
+	fresh, err := v.nonceStore.CheckAndStore(ctx, keyID+":"+nonce, signedAt.Add(v.skew))
+	if err != nil {
+		return "", connect.NewError(connect.CodeUnavailable, fmt.Errorf("could not check nonce: %w", err))
+	}
+	if !fresh {
+		return "", connect.NewError(connect.CodeUnauthenticated, errors.New("replayed request"))
+	}
+	return keyID, nil
+}
@@ -0,0 +1,195 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"github.com/bufbuild/connect-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const procedure = "/acme.foo.v1.FooService/Bar"
+
+// newerProcedure receives a message with fewer fields than the client sends.
+const newerProcedure = "/acme.foo.v1.FooService/Newer"
+
+// newTestServer starts a server verifying the signatures that returns the key
+// ID of the calls.
+func newTestServer(t *testing.T, interceptor connect.Interceptor) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.Handle(procedure, connect.NewUnaryHandler(procedure,
+		func(ctx context.Context, request *connect.Request[wrapperspb.StringValue]) (*connect.Response[wrapperspb.StringValue], error) {
+			keyID, ok := KeyIDFromContext(ctx)
+			assert.True(t, ok)
+			return connect.NewResponse(wrapperspb.String(keyID)), nil
+		},
+		connect.WithInterceptors(interceptor),
+	))
+	// The field 1 of the EnumValueDescriptorProto sent by the client is a
+	// string, unknown to the Duration of the server and marshaled after its
+	// known field 2.
+	mux.Handle(newerProcedure, connect.NewUnaryHandler(newerProcedure,
+		func(ctx context.Context, request *connect.Request[durationpb.Duration]) (*connect.Response[wrapperspb.StringValue], error) {
+			assert.NotEmpty(t, request.Msg.ProtoReflect().GetUnknown())
+			keyID, _ := KeyIDFromContext(ctx)
+			return connect.NewResponse(wrapperspb.String(keyID)), nil
+		},
+		connect.WithInterceptors(interceptor),
+	))
+	server := httptest.NewServer(NewHandler(mux))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// tamperingTransport changes the body after it is signed.
+type tamperingTransport struct {
+	base http.RoundTripper
+}
+
+func (t tamperingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	body = bytes.ReplaceAll(body, []byte("hello"), []byte("jello"))
+	r.Body, r.ContentLength = io.NopCloser(bytes.NewReader(body)), int64(len(body))
+	return t.base.RoundTrip(r)
+}
+
+func TestInterceptors(t *testing.T) {
+	server := newTestServer(t, NewVerifyingInterceptor(StaticSecrets{
+		"2023-01": []byte("old secret"),
+		"2023-02": []byte("new secret"),
+	}))
+	httpClient := &http.Client{Transport: NewTransport(server.Client().Transport)}
+	call := func(interceptors ...connect.Interceptor) (string, error) {
+		client := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](
+			httpClient, server.URL+procedure, connect.WithInterceptors(interceptors...))
+		response, err := client.CallUnary(context.Background(), connect.NewRequest(wrapperspb.String("hello")))
+		if err != nil {
+			return "", err
+		}
+		return response.Msg.Value, nil
+	}
+
+	t.Run("verifies the signatures of each key", func(t *testing.T) {
+		keyID, err := call(NewSigningInterceptor("2023-01", []byte("old secret")))
+		require.NoError(t, err)
+		assert.Equal(t, "2023-01", keyID)
+		keyID, err = call(NewSigningInterceptor("2023-02", []byte("new secret")))
+		require.NoError(t, err)
+		assert.Equal(t, "2023-02", keyID)
+	})
+	t.Run("rejects unsigned requests", func(t *testing.T) {
+		_, err := call()
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+	t.Run("rejects unknown keys", func(t *testing.T) {
+		_, err := call(NewSigningInterceptor("2022-12", []byte("old secret")))
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+	t.Run("rejects wrong secrets", func(t *testing.T) {
+		_, err := call(NewSigningInterceptor("2023-02", []byte("old secret")))
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+	t.Run("rejects tampered bodies", func(t *testing.T) {
+		client := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](
+			&http.Client{Transport: NewTransport(tamperingTransport{server.Client().Transport})},
+			server.URL+procedure, connect.WithInterceptors(NewSigningInterceptor("2023-02", []byte("new secret"))))
+		_, err := client.CallUnary(context.Background(), connect.NewRequest(wrapperspb.String("hello")))
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+	t.Run("rejects requests not signed by the transport", func(t *testing.T) {
+		client := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](
+			server.Client(), server.URL+procedure, connect.WithInterceptors(NewSigningInterceptor("2023-02", []byte("new secret"))))
+		_, err := client.CallUnary(context.Background(), connect.NewRequest(wrapperspb.String("hello")))
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+	t.Run("verifies fields unknown to the server", func(t *testing.T) {
+		client := connect.NewClient[descriptorpb.EnumValueDescriptorProto, wrapperspb.StringValue](
+			httpClient, server.URL+newerProcedure, connect.WithInterceptors(NewSigningInterceptor("2023-02", []byte("new secret"))))
+		response, err := client.CallUnary(context.Background(), connect.NewRequest(&descriptorpb.EnumValueDescriptorProto{
+			Name:   proto.String("hello"),
+			Number: proto.Int32(1),
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, "2023-02", response.Msg.Value)
+	})
+}
+
+type serverRequest struct {
+	*connect.Request[wrapperspb.StringValue]
+}
+
+func (s serverRequest) Spec() connect.Spec {
+	return connect.Spec{Procedure: procedure}
+}
+
+// verify calls a handler wrapped by the interceptor with a request signed at
+// the time with the nonce.
+func verify(interceptor connect.Interceptor, signedAt time.Time, nonce string) error {
+	request := serverRequest{connect.NewRequest(wrapperspb.String("hello"))}
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	bodyDigest := digest([]byte("body"))
+	signature := sign([]byte("secret"), procedure, timestamp, nonce, bodyDigest)
+	ctx := context.WithValue(context.Background(), bodyDigestKey{}, bodyDigest)
+	request.Header().Set(HeaderKeyID, "key")
+	request.Header().Set(HeaderTimestamp, timestamp)
+	request.Header().Set(HeaderNonce, nonce)
+	request.Header().Set(HeaderSignature, signature)
+	_, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		return connect.NewResponse(&wrapperspb.StringValue{}), nil
+	})(ctx, request)
+	return err
+}
+
+func TestNewVerifyingInterceptor(t *testing.T) {
+	now := time.Unix(time.Now().Unix(), 0)
+	nowFunc = func() time.Time { return now }
+	defer func() { nowFunc = time.Now }()
+	interceptor := NewVerifyingInterceptor(StaticSecrets{"key": []byte("secret")}, WithSkew(time.Minute))
+
+	t.Run("enforces the clock skew window", func(t *testing.T) {
+		assert.NoError(t, verify(interceptor, now.Add(-time.Minute), "a"))
+		assert.NoError(t, verify(interceptor, now.Add(time.Minute), "b"))
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(verify(interceptor, now.Add(-2*time.Minute), "c")))
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(verify(interceptor, now.Add(2*time.Minute), "d")))
+	})
+	t.Run("rejects replayed requests", func(t *testing.T) {
+		assert.NoError(t, verify(interceptor, now, "e"))
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(verify(interceptor, now, "e")))
+	})
+	t.Run("rejects streaming calls", func(t *testing.T) {
+		err := interceptor.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+			return nil
+		})(context.Background(), nil)
+		assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	})
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	now := time.Now()
+	nowFunc = func() time.Time { return now }
+	defer func() { nowFunc = time.Now }()
+	store := NewMemoryNonceStore()
+
+	fresh, err := store.CheckAndStore(context.Background(), "a", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, fresh)
+	fresh, _ = store.CheckAndStore(context.Background(), "a", now.Add(time.Minute))
+	assert.False(t, fresh)
+
+	now = now.Add(time.Minute)
+	fresh, _ = store.CheckAndStore(context.Background(), "a", now.Add(time.Minute))
+	assert.True(t, fresh, "expired nonce")
+}
@@ -0,0 +1,126 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"github.com/bufbuild/connect-go"
+	"github.com/bufbuild/protovalidate-go"
+	"google.golang.org/protobuf/proto"
+)
+
+// An Option configures the validation interceptor.
+type Option func(*interceptor)
+
+// WithValidator uses the given protovalidate.Validator instead of a default one.
+func WithValidator(validator *protovalidate.Validator) Option {
+	return func(i *interceptor) {
+		i.validator = validator
+	}
+}
+
+// WithResponseValidation also validates the responses sent by the handlers.
+// Invalid responses are reported to the client as connect.CodeInternal since
+// they are a bug in the server, not in the request.
+func WithResponseValidation() Option {
+	return func(i *interceptor) {
+		i.validateResponses = true
+	}
+}
+
+type interceptor struct {
+	validator         *protovalidate.Validator
+	validateResponses bool
+}
+
+// NewInterceptor returns a connect.Interceptor that validates requests against the
+// buf.validate constraints declared in their proto descriptors. Requests that violate
+// the constraints are rejected with connect.CodeInvalidArgument and a buf.validate.Violations
+// error detail listing the field violations.
+//
+// Messages that are not proto.Message are not validated.
+func NewInterceptor(options ...Option) (connect.Interceptor, error) {
+	i := &interceptor{}
+	for _, option := range options {
+		option(i)
+	}
+	if i.validator == nil {
+		validator, err := protovalidate.New()
+		if err != nil {
+			return nil, err
+		}
+		i.validator = validator
+	}
+	return i, nil
+}
+
+func (i *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		if err := i.validate(request.Any(), connect.CodeInvalidArgument); err != nil {
+			return nil, err
+		}
+		response, err := next(ctx, request)
+		if err != nil || request.Spec().IsClient || !i.validateResponses {
+			return response, err
+		}
+		if err := i.validate(response.Any(), connect.CodeInternal); err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+}
+
+func (i *interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		return next(ctx, &validatingHandler{
+			StreamingHandlerConn: conn,
+			interceptor:          i,
+		})
+	}
+}
+
+// validate returns a connect.Error with the given code if the message violates
+// its constraints.
+func (i *interceptor) validate(msg any, code connect.Code) error {
+	protoMsg, ok := msg.(proto.Message)
+	if !ok {
+		return nil
+	}
+	err := i.validator.Validate(protoMsg)
+	if err == nil {
+		return nil
+	}
+	var validationErr *protovalidate.ValidationError
+	if !errors.As(err, &validationErr) {
+		return connect.NewError(connect.CodeInternal, err)
+	}
+	connectErr := connect.NewError(code, err)
+	if detail, detailErr := connect.NewErrorDetail(validationErr.ToProto()); detailErr == nil {
+		connectErr.AddDetail(detail)
+	}
+	return connectErr
+}
+
+type validatingHandler struct {
+	connect.StreamingHandlerConn
+	interceptor *interceptor
+}
+
+func (v *validatingHandler) Receive(msg any) error {
+	if err := v.StreamingHandlerConn.Receive(msg); err != nil {
+		return err
+	}
+	return v.interceptor.validate(msg, connect.CodeInvalidArgument)
+}
+
+func (v *validatingHandler) Send(msg any) error {
+	if v.interceptor.validateResponses {
+		if err := v.interceptor.validate(msg, connect.CodeInternal); err != nil {
+			return err
+		}
+	}
+	return v.StreamingHandlerConn.Send(msg)
+}
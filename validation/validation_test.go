@@ -0,0 +1,196 @@
+package validation
+
+import (
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"context"
+	"github.com/bufbuild/connect-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"net/http"
+	"testing"
+)
+
+// testMessageDescriptor describes the message
+//
+//	message Message {
+//	  string name = 1 [(buf.validate.field).string.min_len = 1];
+//	}
+func testMessageDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	fieldOptions := &descriptorpb.FieldOptions{}
+	proto.SetExtension(fieldOptions, validate.E_Field, &validate.FieldConstraints{
+		Type: &validate.FieldConstraints_String_{
+			String_: &validate.StringRules{MinLen: proto.Uint64(1)},
+		},
+	})
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("validation/test.proto"),
+		Package:    proto.String("validation.test"),
+		Dependency: []string{"buf/validate/validate.proto"},
+		Syntax:     proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Message"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:     proto.String("name"),
+				JsonName: proto.String("name"),
+				Number:   proto.Int32(1),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				Options:  fieldOptions,
+			}},
+		}},
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	return file.Messages().Get(0)
+}
+
+func newMessage(descriptor protoreflect.MessageDescriptor, name string) *dynamicpb.Message {
+	message := dynamicpb.NewMessage(descriptor)
+	if name != "" {
+		message.Set(descriptor.Fields().ByName("name"), protoreflect.ValueOfString(name))
+	}
+	return message
+}
+
+func assertViolation(t *testing.T, err error, code connect.Code, fieldPath string) {
+	var connectErr *connect.Error
+	require.ErrorAs(t, err, &connectErr)
+	assert.Equal(t, code, connectErr.Code())
+	require.Len(t, connectErr.Details(), 1)
+	value, err := connectErr.Details()[0].Value()
+	require.NoError(t, err)
+	violations, ok := value.(*validate.Violations)
+	require.True(t, ok)
+	require.Len(t, violations.Violations, 1)
+	assert.Equal(t, fieldPath, violations.Violations[0].FieldPath)
+	assert.Equal(t, "string.min_len", violations.Violations[0].ConstraintId)
+}
+
+func TestInterceptor_WrapUnary(t *testing.T) {
+	descriptor := testMessageDescriptor(t)
+	interceptor, err := NewInterceptor()
+	require.NoError(t, err)
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		_, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			t.Fail()
+			return nil, nil
+		})(context.Background(), connect.NewRequest(newMessage(descriptor, "")))
+		assertViolation(t, err, connect.CodeInvalidArgument, "name")
+	})
+
+	t.Run("passes valid requests", func(t *testing.T) {
+		expectedResponse := connect.NewResponse(newMessage(descriptor, ""))
+		response, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			return expectedResponse, nil
+		})(context.Background(), connect.NewRequest(newMessage(descriptor, "foo")))
+		assert.NoError(t, err)
+		assert.Same(t, expectedResponse, response)
+	})
+
+	t.Run("ignores non proto messages", func(t *testing.T) {
+		_, err := interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			return nil, nil
+		})(context.Background(), connect.NewRequest(&map[string]string{}))
+		assert.NoError(t, err)
+	})
+
+	t.Run("validates responses", func(t *testing.T) {
+		interceptor, err := NewInterceptor(WithResponseValidation())
+		require.NoError(t, err)
+		_, err = interceptor.WrapUnary(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			return connect.NewResponse(newMessage(descriptor, "")), nil
+		})(context.Background(), connect.NewRequest(newMessage(descriptor, "foo")))
+		assertViolation(t, err, connect.CodeInternal, "name")
+	})
+}
+
+func TestInterceptor_WrapStreamingHandler(t *testing.T) {
+	descriptor := testMessageDescriptor(t)
+
+	t.Run("rejects invalid messages", func(t *testing.T) {
+		interceptor, err := NewInterceptor()
+		require.NoError(t, err)
+		conn := noOpConn{
+			receive: func(msg any) error {
+				msg.(*dynamicpb.Message).Clear(descriptor.Fields().ByName("name"))
+				return nil
+			},
+		}
+		err = interceptor.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+			return conn.Receive(newMessage(descriptor, "foo"))
+		})(context.Background(), conn)
+		assertViolation(t, err, connect.CodeInvalidArgument, "name")
+	})
+
+	t.Run("validates responses", func(t *testing.T) {
+		interceptor, err := NewInterceptor(WithResponseValidation())
+		require.NoError(t, err)
+		conn := noOpConn{
+			send: func(msg any) error {
+				t.Fail()
+				return nil
+			},
+		}
+		err = interceptor.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+			return conn.Send(newMessage(descriptor, ""))
+		})(context.Background(), conn)
+		assertViolation(t, err, connect.CodeInternal, "name")
+	})
+
+	t.Run("passes valid messages", func(t *testing.T) {
+		interceptor, err := NewInterceptor(WithResponseValidation())
+		require.NoError(t, err)
+		err = interceptor.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+			if err := conn.Receive(newMessage(descriptor, "foo")); err != nil {
+				return err
+			}
+			return conn.Send(newMessage(descriptor, "bar"))
+		})(context.Background(), noOpConn{})
+		assert.NoError(t, err)
+	})
+}
+
+type noOpConn struct {
+	receive func(msg any) error
+	send    func(msg any) error
+}
+
+func (n noOpConn) Spec() connect.Spec {
+	return connect.Spec{}
+}
+
+func (n noOpConn) Peer() connect.Peer {
+	return connect.Peer{}
+}
+
+func (n noOpConn) Receive(msg any) error {
+	if n.receive != nil {
+		return n.receive(msg)
+	}
+	return nil
+}
+
+func (n noOpConn) RequestHeader() http.Header {
+	return http.Header{}
+}
+
+func (n noOpConn) Send(msg any) error {
+	if n.send != nil {
+		return n.send(msg)
+	}
+	return nil
+}
+
+func (n noOpConn) ResponseHeader() http.Header {
+	return http.Header{}
+}
+
+func (n noOpConn) ResponseTrailer() http.Header {
+	return http.Header{}
+}